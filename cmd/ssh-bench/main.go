@@ -0,0 +1,156 @@
+//go:build linux
+
+// ssh-bench - throughput benchmark of the SSH tunnel's packet bridge.
+//
+// Starts an SSH server on 127.0.0.1 that reflects every packet back to the
+// client, connects a real SSH tunnel to it (a TUN adapter, the packet
+// bridge and the SSH transport, as in the client) and floods it with UDP
+// datagrams from several sockets. The datagrams sent, the ones that came
+// back and the tunnel statistics are reported. Needs root for the TUN
+// adapter; run it with -race to check the bridge for data races.
+//
+// Usage:
+//
+//	ssh-bench [-mode script|native] [-size 1400] [-senders 1] [-duration 10s]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/protocols/ssh"
+)
+
+const (
+	localAddr  = "10.77.0.2"
+	serverAddr = "10.77.0.1"
+	peerAddr   = "10.77.0.3" // routed into the tunnel, reflected back as the source
+)
+
+func main() {
+	mode := flag.String("mode", config.SSHTunnelScript, "tunnel mode: script or native")
+	size := flag.Int("size", 1400, "IP packet size in bytes")
+	senders := flag.Int("senders", 1, "number of sending sockets")
+	duration := flag.Duration("duration", 10*time.Second, "how long to send")
+	iface := flag.String("iface", "sshbench0", "name of the TUN adapter")
+	flag.Parse()
+
+	log.SetFlags(0)
+	log.SetPrefix("ssh-bench: ")
+
+	if *size < 28+1 || *size > 1420 {
+		log.Fatal("-size must be between 29 and 1420")
+	}
+
+	srv, err := newLoopbackServer()
+	if err != nil {
+		log.Fatalf("failed to start SSH server: %v", err)
+	}
+	defer srv.close()
+
+	tunnel := ssh.New(&config.SSH{
+		Host: "127.0.0.1",
+		Port: srv.port(),
+		User: "bench",
+		SSHAuth: config.SSHAuth{
+			Password:            "bench",
+			AuthMethods:         []string{"password"},
+			HostKeyFingerprints: []string{srv.fingerprint},
+		},
+		LocalTunAddr:  localAddr + "/24",
+		RemoteTunAddr: serverAddr,
+		TunnelMode:    *mode,
+		RemoteHelper:  config.SSHHelperPython,
+	}, &config.Interface{
+		Name:   *iface,
+		MTU:    1420,
+		Metric: 5,
+	})
+	if err := tunnel.Start(context.Background()); err != nil {
+		log.Fatalf("failed to start tunnel: %v", err)
+	}
+	defer tunnel.Stop()
+
+	var (
+		sent, received atomic.Uint64
+		wg             sync.WaitGroup
+	)
+	payload := make([]byte, *size-28) // IPv4 and UDP headers
+	dst := &net.UDPAddr{IP: net.ParseIP(peerAddr), Port: 9}
+	deadline := time.Now().Add(*duration)
+
+	for i := 0; i < *senders; i++ {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.ParseIP(localAddr)})
+		if err != nil {
+			log.Fatalf("failed to open socket: %v", err)
+		}
+		conn.SetReadBuffer(4 << 20)
+		defer conn.Close()
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				if _, err := conn.WriteTo(payload, dst); err == nil {
+					sent.Add(1)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, 65535)
+			conn.SetReadDeadline(deadline.Add(time.Second))
+			for {
+				if _, _, err := conn.ReadFrom(buf); err != nil {
+					return
+				}
+				received.Add(1)
+			}
+		}()
+	}
+
+	// Read the statistics while the bridge updates them, as the status
+	// sampler does
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		var last uint64
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				bytes := tunnel.Stats().BytesReceived
+				fmt.Printf("%-10s %.1f Mbit/s\n", "tunnel", float64(bytes-last)*8/1e6)
+				last = bytes
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	secs := duration.Seconds()
+
+	stats := tunnel.Stats()
+	fmt.Printf("mode %s, %d-byte packets, %d senders, %s\n", *mode, *size, *senders, *duration)
+	report("sent", sent.Load(), *size, secs)
+	report("received", received.Load(), *size, secs)
+	if n := sent.Load(); n > 0 {
+		fmt.Printf("%-10s %.1f%%\n", "lost", 100*(1-float64(received.Load())/float64(n)))
+	}
+	fmt.Printf("%-10s %d packets / %d bytes sent, %d packets / %d bytes received\n", "tunnel",
+		stats.PacketsSent, stats.BytesSent, stats.PacketsRecv, stats.BytesReceived)
+}
+
+func report(what string, packets uint64, size int, secs float64) {
+	fmt.Printf("%-10s %d packets, %.0f packets/s, %.1f Mbit/s\n", what, packets,
+		float64(packets)/secs, float64(packets)*float64(size)*8/secs/1e6)
+}
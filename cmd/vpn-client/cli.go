@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command is a CLI subcommand group such as "wg".
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"ssh": {usage: sshUsage, run: runSSH},
+	"wg":  {usage: wgUsage, run: runWG},
+}
+
+// isCommand reports whether args start with a CLI subcommand.
+func isCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	_, ok := commands[args[0]]
+	return ok || args[0] == "help" || args[0] == "-h" || args[0] == "--help"
+}
+
+// runCommand executes a CLI subcommand and returns the process exit code.
+func runCommand(args []string) int {
+	attachConsole()
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		printUsage()
+		return 0
+	}
+	if err := cmd.run(args[1:]); err != nil {
+		if err == errUsage {
+			fmt.Fprint(os.Stderr, cmd.usage)
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// errUsage makes runCommand print the subcommand usage.
+var errUsage = errors.New("usage")
+
+func printUsage() {
+	fmt.Println("Usage: vpn-client [command]")
+	fmt.Println()
+	fmt.Println("Without a command the GUI is started.")
+	fmt.Println()
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Print(commands[name].usage)
+	}
+}
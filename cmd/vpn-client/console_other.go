@@ -0,0 +1,6 @@
+//go:build !windows
+
+package main
+
+// attachConsole is a no-op: the binary always inherits the terminal.
+func attachConsole() {}
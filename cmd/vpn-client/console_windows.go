@@ -0,0 +1,40 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// attachConsole connects the GUI-subsystem binary to the console of the
+// shell that started it, so CLI output is visible. Redirected standard
+// handles are kept as they are.
+func attachConsole() {
+	const attachParentProcess = ^uintptr(0)
+	proc := windows.NewLazySystemDLL("kernel32.dll").NewProc("AttachConsole")
+	if r, _, _ := proc.Call(attachParentProcess); r == 0 {
+		return
+	}
+	if !hasStdHandle(windows.STD_OUTPUT_HANDLE) {
+		if f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+			os.Stdout = f
+		}
+	}
+	if !hasStdHandle(windows.STD_ERROR_HANDLE) {
+		if f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+			os.Stderr = f
+		}
+	}
+	if !hasStdHandle(windows.STD_INPUT_HANDLE) {
+		if f, err := os.OpenFile("CONIN$", os.O_RDONLY, 0); err == nil {
+			os.Stdin = f
+		}
+	}
+}
+
+func hasStdHandle(which uint32) bool {
+	h, err := windows.GetStdHandle(which)
+	return err == nil && h != 0 && h != windows.InvalidHandle
+}
@@ -4,17 +4,25 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
 
+	"github.com/user/vpn-client/internal/config"
 	"github.com/user/vpn-client/internal/elevate"
 	"github.com/user/vpn-client/internal/ui"
 	wintundll "github.com/user/vpn-client/resources"
 )
 
 func main() {
+	// CLI subcommands run unprivileged and never start the GUI.
+	if isCommand(os.Args[1:]) {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	fmt.Println("VPN Client starting...")
 
-	// VPN requires admin/root for TUN, routing, DNS and firewall
-	if !elevate.IsAdmin() {
+	// VPN requires admin/root for TUN, routing, DNS and firewall.
+	// Userspace protocols run without elevation.
+	if needsAdmin() && !elevate.IsAdmin() {
 		fmt.Println("Not running as administrator, requesting elevation...")
 		if err := elevate.RunAsAdmin(); err != nil {
 			log.Fatalf("Failed to elevate privileges: %v\nPlease run as administrator.", err)
@@ -29,3 +37,13 @@ func main() {
 
 	ui.Run()
 }
+
+// needsAdmin reports whether the configured protocol requires elevated
+// privileges. An unreadable config is treated as privileged.
+func needsAdmin() bool {
+	m := config.NewManager(config.GetConfigPath())
+	if err := m.Load(); err != nil {
+		return true
+	}
+	return m.Get().Protocol.RequiresAdmin()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/protocols"
+	"github.com/user/vpn-client/internal/protocols/ssh"
+)
+
+const sshUsage = `SSH tunnel maintenance:
+  vpn-client ssh cleanup-remote [-stale]
+                         revert NAT rules, ip_forward and tun devices the
+                         tunnel left on the server; -stale keeps sessions
+                         that are still running
+`
+
+func runSSH(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "cleanup-remote":
+		onlyStale := false
+		for _, a := range args[1:] {
+			if a != "-stale" {
+				return errUsage
+			}
+			onlyStale = true
+		}
+		return sshCleanupRemote(onlyStale)
+	default:
+		return errUsage
+	}
+}
+
+// sshCleanupRemote reverts the server changes recorded by script-mode
+// sessions, e.g. after the client crashed.
+func sshCleanupRemote(onlyStale bool) error {
+	m := config.NewManager(config.GetConfigPath())
+	if err := m.Load(); err != nil {
+		return err
+	}
+	cfg := m.Get()
+
+	reverted, err := ssh.CleanupRemote(&cfg.SSH, onlyStale, terminalPrompt)
+	if err != nil {
+		return err
+	}
+	if len(reverted) == 0 {
+		fmt.Println("Nothing to clean up.")
+		return nil
+	}
+	for _, line := range reverted {
+		fmt.Printf("reverted %s\n", line)
+	}
+	return nil
+}
+
+// terminalPrompt asks on the terminal, hiding secret answers.
+func terminalPrompt(p protocols.Prompt) (string, bool) {
+	fmt.Fprintf(os.Stderr, "%s: %s ", p.Title, p.Message)
+
+	fd := int(os.Stdin.Fd())
+	if !p.Echo && term.IsTerminal(fd) {
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(b), err == nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", false
+	}
+	return strings.TrimRight(line, "\r\n"), true
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/protocols/wireguard"
+)
+
+const wgUsage = `WireGuard key management:
+  vpn-client wg genkey   print a new private key
+  vpn-client wg pubkey   read a private key from stdin, print its public key
+  vpn-client wg genpsk   print a new preshared key
+  vpn-client wg show     show the configured key and the running interface
+  vpn-client wg rotate   write a new keypair into the profile, print the public key
+`
+
+func runWG(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "genkey":
+		key, err := wireguard.GeneratePrivateKey()
+		if err != nil {
+			return err
+		}
+		fmt.Println(key)
+	case "genpsk":
+		key, err := wireguard.GeneratePresharedKey()
+		if err != nil {
+			return err
+		}
+		fmt.Println(key)
+	case "pubkey":
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read private key: %w", err)
+		}
+		pub, err := wireguard.PublicKey(strings.TrimSpace(line))
+		if err != nil {
+			return err
+		}
+		fmt.Println(pub)
+	case "show":
+		return wgShow()
+	case "rotate":
+		return wgRotate()
+	default:
+		return errUsage
+	}
+	return nil
+}
+
+// wgShow prints the configured interface and, if it is up, the state of
+// the running device.
+func wgShow() error {
+	m := config.NewManager(config.GetConfigPath())
+	if err := m.Load(); err != nil {
+		return err
+	}
+	cfg := m.Get()
+	var pub string
+	if cfg.WireGuard.PrivateKey != "" {
+		var err error
+		if pub, err = wireguard.PublicKey(cfg.WireGuard.PrivateKey); err != nil {
+			return err
+		}
+	}
+
+	// The running adapter may carry a kernel-assigned name
+	iface, status, err := wireguard.FindDevice(cfg.Interface.Name, pub)
+	if err != nil {
+		iface = cfg.Interface.Name
+	}
+	fmt.Printf("interface: %s\n", iface)
+	if pub != "" {
+		fmt.Printf("  public key: %s\n", pub)
+	}
+	if err != nil {
+		fmt.Printf("  state: down\n")
+		if cfg.WireGuard.Peer.PublicKey != "" {
+			fmt.Printf("\npeer: %s\n", cfg.WireGuard.Peer.PublicKey)
+			fmt.Printf("  endpoint: %s\n", cfg.WireGuard.Peer.Endpoint)
+		}
+		return nil
+	}
+
+	fmt.Printf("  state: up\n")
+	if status.ListenPort != 0 {
+		fmt.Printf("  listening port: %d\n", status.ListenPort)
+	}
+	for _, p := range status.Peers {
+		fmt.Printf("\npeer: %s\n", p.PublicKey)
+		if p.Endpoint != "" {
+			fmt.Printf("  endpoint: %s\n", p.Endpoint)
+		}
+		if len(p.AllowedIPs) > 0 {
+			fmt.Printf("  allowed ips: %s\n", strings.Join(p.AllowedIPs, ", "))
+		}
+		if p.LastHandshake.IsZero() {
+			fmt.Printf("  latest handshake: never\n")
+		} else {
+			fmt.Printf("  latest handshake: %s ago\n", time.Since(p.LastHandshake).Round(time.Second))
+		}
+		fmt.Printf("  transfer: %s received, %s sent\n", formatBytes(p.RxBytes), formatBytes(p.TxBytes))
+		if p.PersistentKeepalive > 0 {
+			fmt.Printf("  persistent keepalive: every %d seconds\n", p.PersistentKeepalive)
+		}
+	}
+	return nil
+}
+
+// wgRotate replaces the profile's private key and prints the new public
+// key, which has to be registered on the server.
+func wgRotate() error {
+	m := config.NewManager(config.GetConfigPath())
+	if err := m.Load(); err != nil {
+		return err
+	}
+
+	priv, err := wireguard.GeneratePrivateKey()
+	if err != nil {
+		return err
+	}
+	pub, err := wireguard.PublicKey(priv)
+	if err != nil {
+		return err
+	}
+
+	m.Get().WireGuard.PrivateKey = priv
+	if err := m.Save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "New keypair written to %s.\n", config.GetConfigPath())
+	fmt.Fprintf(os.Stderr, "Send this public key to the server administrator and reconnect once it is registered:\n")
+	fmt.Println(pub)
+	return nil
+}
+
+func formatBytes(b uint64) string {
+	const (
+		KB = 1024
+		MB = 1024 * 1024
+		GB = 1024 * 1024 * 1024
+	)
+	switch {
+	case b >= GB:
+		return fmt.Sprintf("%.2f GiB", float64(b)/float64(GB))
+	case b >= MB:
+		return fmt.Sprintf("%.2f MiB", float64(b)/float64(MB))
+	case b >= KB:
+		return fmt.Sprintf("%.2f KiB", float64(b)/float64(KB))
+	default:
+		return fmt.Sprintf("%d B", b)
+	}
+}
@@ -0,0 +1,152 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// lockDir holds one lock per client address in use on this server.
+	lockDir = "/run/vpn-client"
+
+	// maxDevices is how many devices one user may have at a time.
+	maxDevices = 100
+
+	// maxPool limits how many addresses are tried in large pools.
+	maxPool = 1024
+)
+
+// allocateAddress picks the client address for this session: the preferred
+// one if it is free, otherwise the next free address of its prefix. The
+// address is locked until the process exits.
+func allocateAddress(preferred, server string) (string, error) {
+	pfx, err := netip.ParsePrefix(preferred)
+	if err != nil {
+		addr, aerr := netip.ParseAddr(preferred)
+		if aerr != nil {
+			return "", fmt.Errorf("invalid client address %q", preferred)
+		}
+		pfx = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	if err := os.MkdirAll(lockDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", lockDir, err)
+	}
+	used, err := addressesInUse()
+	if err != nil {
+		return "", err
+	}
+
+	try := func(addr netip.Addr) bool {
+		s := addr.String()
+		if s == server || used[s] {
+			return false
+		}
+		return lockAddress(s)
+	}
+
+	if try(pfx.Addr()) {
+		return pfx.Addr().String(), nil
+	}
+	network := pfx.Masked()
+	hostBits := network.Addr().BitLen() - network.Bits()
+	for addr, i := network.Addr(), 0; network.Contains(addr) && i < maxPool; addr, i = addr.Next(), i+1 {
+		if hostBits >= 2 && (addr == network.Addr() || !network.Contains(addr.Next())) {
+			continue // network and broadcast addresses
+		}
+		if try(addr) {
+			return addr.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no free client address in %s", network)
+}
+
+// lockAddress takes the lock for a client address. The descriptor is left
+// open on purpose: the kernel drops the lock when the helper exits.
+func lockAddress(addr string) bool {
+	path := filepath.Join(lockDir, "addr-"+addr+".lock")
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_CREAT, 0644)
+	if err != nil {
+		return false
+	}
+	if err := unix.Flock(fd, unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		unix.Close(fd)
+		return false
+	}
+	unix.Write(fd, []byte(strconv.Itoa(os.Getpid())+"\n"))
+	return true
+}
+
+// addressesInUse returns the addresses configured on any interface,
+// including the peers of point-to-point links set up without this helper.
+func addressesInUse() (map[string]bool, error) {
+	out, err := exec.Command("ip", "-o", "-4", "addr", "show").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses: %w", err)
+	}
+	used := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		for i := 0; i+1 < len(fields); i++ {
+			if fields[i] == "inet" || fields[i] == "peer" {
+				addr, _, _ := strings.Cut(fields[i+1], "/")
+				used[addr] = true
+			}
+		}
+	}
+	return used, nil
+}
+
+// createTun creates the first free device named after the login user.
+// Existing devices are never touched: they may belong to other sessions.
+func createTun() (*os.File, string, error) {
+	uid := os.Getenv("SUDO_UID")
+	if uid == "" {
+		uid = strconv.Itoa(os.Getuid())
+	}
+
+	for n := 0; n < maxDevices; n++ {
+		name := fmt.Sprintf("vpn%s-%d", uid, n)
+		if len(name) >= unix.IFNAMSIZ {
+			return nil, "", fmt.Errorf("device name %s is too long", name)
+		}
+		if _, err := os.Stat("/sys/class/net/" + name); err == nil {
+			continue
+		}
+		f, err := openTun(name)
+		if err == nil {
+			return f, name, nil
+		}
+		if !errors.Is(err, unix.EBUSY) && !errors.Is(err, unix.EEXIST) {
+			return nil, "", fmt.Errorf("failed to create TUN device %s: %w", name, err)
+		}
+	}
+	return nil, "", fmt.Errorf("no free TUN device for uid %s", uid)
+}
+
+func openTun(name string) (*os.File, error) {
+	fd, err := unix.Open("/dev/net/tun", unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	ifr, err := unix.NewIfreq(name)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	ifr.SetUint16(unix.IFF_TUN | unix.IFF_NO_PI)
+	if err := unix.IoctlIfreq(fd, unix.TUNSETIFF, ifr); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), "/dev/net/tun"), nil
+}
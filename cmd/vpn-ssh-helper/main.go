@@ -0,0 +1,201 @@
+//go:build linux
+
+// vpn-ssh-helper - server side of the SSH tunnel (tunnel_mode: script).
+//
+// The client uploads this static binary over SFTP and runs it via sudo in
+// an exec session. It enables IPv4 forwarding and NAT for the client
+// address, creates the TUN device, prints the handshake line and then
+// forwards length-framed packets between stdin/stdout and the device
+// until stdin is closed. Every change is appended to the journal file,
+// from which the client reverts it when the session ends. ip_forward is
+// shared by all sessions, so its previous value is kept host-wide in
+// /run/vpn-client/forward instead.
+//
+// Several clients can share a server: each session gets its own device
+// named after the login user (vpn<uid>-<n>) and a client address locked
+// under /run/vpn-client, so sessions never touch each other's devices.
+//
+// Usage:
+//
+//	vpn-ssh-helper -local 10.0.0.1 -peer 10.0.0.2/24 [-nat] [-journal FILE]
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/user/vpn-client/internal/sshhelper"
+)
+
+func main() {
+	local := flag.String("local", "10.0.0.1", "server address on the TUN device")
+	peer := flag.String("peer", "10.0.0.2/24", "preferred client address; its prefix is the pool to pick a free one from")
+	nat := flag.Bool("nat", false, "masquerade traffic from the client address")
+	journal := flag.String("journal", "", "file to record changes in for later cleanup")
+	flag.Parse()
+
+	log.SetFlags(0)
+	log.SetPrefix("vpn-ssh-helper: ")
+
+	j := &changeLog{path: *journal}
+
+	// The lock is held until the process exits
+	client, err := allocateAddress(*peer, *local)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := enableForwarding(); err != nil {
+		log.Fatal(err)
+	}
+	if *nat {
+		if err := masquerade(client, j); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	tun, name, err := createTun()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("using TUN device %s, client address %s", name, client)
+	j.record("tun", name)
+
+	if err := run("ip", "addr", "add", *local, "peer", client, "dev", name); err != nil {
+		log.Fatal(err)
+	}
+	if err := run("ip", "link", "set", name, "up"); err != nil {
+		log.Fatal(err)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	go func() {
+		<-sig
+		os.Exit(0)
+	}()
+
+	if _, err := os.Stdout.WriteString(sshhelper.ReadyLine(name, client)); err != nil {
+		log.Fatalf("failed to write handshake: %v", err)
+	}
+
+	go tunToStdout(tun)
+	stdinToTun(tun)
+}
+
+// changeLog appends "<kind> <value>" lines to the session journal.
+type changeLog struct {
+	path string
+}
+
+func (c *changeLog) record(kind, value string) {
+	if c.path == "" {
+		return
+	}
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		log.Printf("failed to open journal: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "%s %s\n", kind, value); err != nil {
+		log.Printf("failed to write journal: %v", err)
+	}
+}
+
+// enableForwarding turns on IPv4 forwarding. The value before the first
+// session enabled it goes to lockDir/forward, from where the client
+// restores it once no address lock is held by any user.
+func enableForwarding() error {
+	const path = "/proc/sys/net/ipv4/ip_forward"
+	old, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read ip_forward: %w", err)
+	}
+	if strings.TrimSpace(string(old)) == "1" {
+		return nil
+	}
+	f, err := os.OpenFile(filepath.Join(lockDir, "forward"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err == nil {
+		_, err = f.Write(old)
+		f.Close()
+	}
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("failed to record ip_forward: %w", err)
+	}
+	if err := os.WriteFile(path, []byte("1\n"), 0644); err != nil {
+		return fmt.Errorf("failed to enable ip_forward: %w", err)
+	}
+	return nil
+}
+
+// masquerade adds the NAT rule for src unless it already exists.
+func masquerade(src string, j *changeLog) error {
+	rule := []string{"POSTROUTING", "-s", src, "-j", "MASQUERADE"}
+	check := append([]string{"-t", "nat", "-C"}, rule...)
+	if exec.Command("iptables", check...).Run() == nil {
+		return nil
+	}
+	if err := run("iptables", append([]string{"-t", "nat", "-A"}, rule...)...); err != nil {
+		return err
+	}
+	j.record("nat", src)
+	return nil
+}
+
+// tunToStdout sends packets from the device to the client.
+func tunToStdout(tun *os.File) {
+	buf := make([]byte, 2+65535)
+	for {
+		n, err := tun.Read(buf[2:])
+		if err != nil {
+			log.Fatalf("TUN read failed: %v", err)
+		}
+		if n == 0 {
+			continue
+		}
+		buf[0] = byte(n >> 8)
+		buf[1] = byte(n)
+		if _, err := os.Stdout.Write(buf[:2+n]); err != nil {
+			os.Exit(0) // client went away
+		}
+	}
+}
+
+// stdinToTun writes packets from the client to the device and returns
+// when the client closes the stream.
+func stdinToTun(tun *os.File) {
+	r := bufio.NewReaderSize(os.Stdin, 256*1024)
+	lenBuf := make([]byte, 2)
+	pkt := make([]byte, 65535)
+	for {
+		if _, err := io.ReadFull(r, lenBuf); err != nil {
+			return
+		}
+		n := int(lenBuf[0])<<8 | int(lenBuf[1])
+		if _, err := io.ReadFull(r, pkt[:n]); err != nil {
+			return
+		}
+		if _, err := tun.Write(pkt[:n]); err != nil {
+			log.Printf("TUN write failed: %v", err)
+		}
+	}
+}
+
+func run(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
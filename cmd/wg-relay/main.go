@@ -0,0 +1,65 @@
+// wg-relay - WireGuard TCP/WebSocket relay.
+//
+// Runs next to a WireGuard server and forwards datagrams received over
+// length-framed TCP or WebSocket streams to the server's UDP port, for
+// clients configured with wireguard.transport: tcp or websocket.
+//
+// Usage:
+//
+//	wg-relay -listen :443 -target 127.0.0.1:51820
+//	wg-relay -listen :443 -target 127.0.0.1:51820 -ws-path /wg [-tls-cert cert.pem -tls-key key.pem]
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/user/vpn-client/internal/relay"
+)
+
+func main() {
+	listen := flag.String("listen", ":8443", "TCP address to accept relay streams on")
+	target := flag.String("target", "127.0.0.1:51820", "WireGuard server UDP address")
+	wsPath := flag.String("ws-path", "", "serve WebSocket on this HTTP path instead of raw TCP")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file (WebSocket mode)")
+	tlsKey := flag.String("tls-key", "", "TLS key file (WebSocket mode)")
+	flag.Parse()
+
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Fatal("-tls-cert and -tls-key must be used together")
+	}
+	if *tlsCert != "" && *wsPath == "" {
+		log.Fatal("TLS is only supported in WebSocket mode (-ws-path)")
+	}
+
+	srv := relay.NewServer(&relay.ServerConfig{
+		Listen:  *listen,
+		Target:  *target,
+		WSPath:  *wsPath,
+		TLSCert: *tlsCert,
+		TLSKey:  *tlsKey,
+		OnStarted: func(addr net.Addr) {
+			mode := "tcp"
+			if *wsPath != "" {
+				mode = "websocket " + *wsPath
+			}
+			log.Printf("Relaying %s (%s) -> udp %s", addr, mode, *target)
+		},
+	})
+
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		log.Printf("Shutting down")
+		srv.Close()
+	}()
+
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatal(err)
+	}
+}
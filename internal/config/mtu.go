@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MTU is an interface MTU. In YAML it is a number or "auto".
+type MTU int
+
+// MTUAuto selects path MTU discovery towards the server.
+const MTUAuto MTU = -1
+
+// DefaultMTU is used when no MTU is configured or discovery fails.
+const DefaultMTU = 1420
+
+// IsAuto reports whether the MTU is discovered at connect time.
+func (m MTU) IsAuto() bool {
+	return m == MTUAuto
+}
+
+// Value returns the fixed MTU, or DefaultMTU for auto and unset values.
+func (m MTU) Value() int {
+	if m <= 0 {
+		return DefaultMTU
+	}
+	return int(m)
+}
+
+// String returns "auto" or the numeric value.
+func (m MTU) String() string {
+	if m.IsAuto() {
+		return "auto"
+	}
+	return strconv.Itoa(int(m))
+}
+
+// ParseMTU parses a number or "auto".
+func ParseMTU(s string) (MTU, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "auto") {
+		return MTUAuto, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid mtu %q: must be a number or auto", s)
+	}
+	return MTU(n), nil
+}
+
+// UnmarshalYAML accepts a number or "auto".
+func (m *MTU) UnmarshalYAML(node *yaml.Node) error {
+	v, err := ParseMTU(node.Value)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// MarshalYAML writes "auto" or the numeric value.
+func (m MTU) MarshalYAML() (interface{}, error) {
+	if m.IsAuto() {
+		return "auto", nil
+	}
+	return int(m), nil
+}
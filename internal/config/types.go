@@ -1,6 +1,13 @@
 // Package config handles VPN client configuration loading, saving, and validation.
 package config
 
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
 // Protocol represents the VPN protocol type.
 type Protocol string
 
@@ -8,8 +15,22 @@ const (
 	ProtocolWireGuard Protocol = "wireguard"
 	ProtocolOpenVPN   Protocol = "openvpn"
 	ProtocolSSH       Protocol = "ssh"
+
+	// ProtocolWireGuardUserspace runs WireGuard on a userspace network stack
+	// and exposes local proxies instead of a TUN adapter.
+	ProtocolWireGuardUserspace Protocol = "wireguard-userspace"
+
+	// ProtocolSSHProxy forwards connections from local proxies through SSH
+	// channels (like ssh -D) without a TUN adapter.
+	ProtocolSSHProxy Protocol = "ssh-proxy"
 )
 
+// RequiresAdmin reports whether the protocol needs elevated privileges for
+// TUN, routing, DNS and firewall changes.
+func (p Protocol) RequiresAdmin() bool {
+	return p != ProtocolWireGuardUserspace && p != ProtocolSSHProxy
+}
+
 // Config represents the main configuration structure.
 type Config struct {
 	Version    int              `yaml:"version"`
@@ -22,6 +43,7 @@ type Config struct {
 	DNS        DNS              `yaml:"dns"`
 	Interface  Interface        `yaml:"interface"`
 	KillSwitch KillSwitchConfig `yaml:"killswitch"`
+	Proxy      Proxy            `yaml:"proxy,omitempty"`
 }
 
 // WireGuard configuration.
@@ -30,6 +52,76 @@ type WireGuard struct {
 	Address    string        `yaml:"address"`
 	DNS        string        `yaml:"dns,omitempty"`
 	Peer       WireGuardPeer `yaml:"peer"`
+
+	// Transport carries WireGuard over a stream when UDP is blocked.
+	Transport string `yaml:"transport,omitempty"` // udp (default), tcp or websocket
+	Relay     string `yaml:"relay,omitempty"`     // tcp: host:port, websocket: ws(s)://host[:port]/path
+
+	// AmneziaWG-compatible obfuscation. All zero = plain WireGuard.
+	Jc   int    `yaml:"jc,omitempty"`   // junk packets sent before each handshake initiation
+	Jmin int    `yaml:"jmin,omitempty"` // minimum junk packet size
+	Jmax int    `yaml:"jmax,omitempty"` // maximum junk packet size
+	S1   int    `yaml:"s1,omitempty"`   // random prefix length of handshake initiation
+	S2   int    `yaml:"s2,omitempty"`   // random prefix length of handshake response
+	H1   uint32 `yaml:"h1,omitempty"`   // message type of handshake initiation (default 1)
+	H2   uint32 `yaml:"h2,omitempty"`   // message type of handshake response (default 2)
+	H3   uint32 `yaml:"h3,omitempty"`   // message type of cookie reply (default 3)
+	H4   uint32 `yaml:"h4,omitempty"`   // message type of transport data (default 4)
+
+	// FwMark marks the tunnel's own packets for Linux policy routing;
+	// it is also the number of the routing table. 0 = DefaultFwMark.
+	FwMark uint32 `yaml:"fwmark,omitempty"`
+}
+
+// DefaultFwMark is the fwmark and routing table used by wg-quick.
+const DefaultFwMark = 51820
+
+// WireGuard transports.
+const (
+	WireGuardTransportUDP       = "udp"
+	WireGuardTransportTCP       = "tcp"
+	WireGuardTransportWebSocket = "websocket"
+)
+
+// UsesRelay reports whether WireGuard is carried over a relay stream.
+func (w *WireGuard) UsesRelay() bool {
+	return w.Transport == WireGuardTransportTCP || w.Transport == WireGuardTransportWebSocket
+}
+
+// DialAddress returns the host:port the client actually connects to:
+// the relay for stream transports, otherwise the peer endpoint.
+func (w *WireGuard) DialAddress() string {
+	if !w.UsesRelay() {
+		return w.Peer.Endpoint
+	}
+	if w.Transport == WireGuardTransportWebSocket {
+		u, err := url.Parse(w.Relay)
+		if err != nil {
+			return ""
+		}
+		if u.Port() != "" {
+			return u.Host
+		}
+		if u.Scheme == "wss" {
+			return net.JoinHostPort(u.Hostname(), "443")
+		}
+		return net.JoinHostPort(u.Hostname(), "80")
+	}
+	return w.Relay
+}
+
+// Obfuscated reports whether any AmneziaWG obfuscation parameter is set.
+func (w *WireGuard) Obfuscated() bool {
+	return w.Jc > 0 || w.S1 > 0 || w.S2 > 0 ||
+		w.H1 > 1 || w.H2 > 2 || w.H3 > 3 || w.H4 > 4
+}
+
+// Mark returns the configured fwmark or DefaultFwMark.
+func (w *WireGuard) Mark() uint32 {
+	if w.FwMark == 0 {
+		return DefaultFwMark
+	}
+	return w.FwMark
 }
 
 // WireGuardPeer represents a WireGuard peer configuration.
@@ -45,6 +137,14 @@ type OpenVPN struct {
 	ConfigPath string `yaml:"config_path,omitempty"`
 	AuthUser   string `yaml:"auth_user,omitempty"`
 	AuthPass   string `yaml:"auth_pass,omitempty"`
+
+	// TOTPSecret is a base32 secret used to answer static challenges
+	// (static-challenge in the profile) without asking the user.
+	TOTPSecret string `yaml:"totp_secret,omitempty"`
+
+	// IgnorePush drops routes and DNS settings pushed by the server so
+	// the local routing and dns sections win.
+	IgnorePush bool `yaml:"ignore_push,omitempty"`
 }
 
 // SSH tunnel configuration.
@@ -52,13 +152,90 @@ type SSH struct {
 	Host              string `yaml:"host"`
 	Port              int    `yaml:"port"`
 	User              string `yaml:"user"`
-	KeyPath           string `yaml:"key_path,omitempty"`
-	Password          string `yaml:"password,omitempty"`
+	SSHAuth           `yaml:",inline"`
 	RemoteTunAddr     string `yaml:"remote_tun_addr,omitempty"`
 	LocalTunAddr      string `yaml:"local_tun_addr,omitempty"`
 	RoutingFile       string `yaml:"routing_file,omitempty"`       // Path to routing file on the remote server
 	KeepAliveInterval int    `yaml:"keepalive_interval,omitempty"` // seconds, 0 = use default (10)
 	KeepAliveRetries  int    `yaml:"keepalive_retries,omitempty"`  // missed pings before reconnect, 0 = use default (3)
+
+	// TunnelMode selects how packets reach the server: "script" (default)
+	// runs a forwarding helper over an exec session, "native" opens the
+	// OpenSSH tun@openssh.com channel (PermitTunnel) and runs nothing.
+	TunnelMode string `yaml:"tunnel_mode,omitempty"`
+	TunUnit    string `yaml:"tun_unit,omitempty"` // remote tunN in native mode: a number or "any" (default)
+
+	// RemoteHelper picks the forwarder of script mode: "auto" (default)
+	// uploads the Go helper and falls back to Python, "go" or "python"
+	// force one of them.
+	RemoteHelper string `yaml:"remote_helper,omitempty"`
+
+	// JumpHosts are bastions the server is reached through, first hop
+	// first (like ProxyJump). The kill switch and server route target the
+	// first hop.
+	JumpHosts []SSHJumpHost `yaml:"jump_hosts,omitempty"`
+
+	// Forwards are TCP port forwards kept over the tunnel's connection and
+	// re-established after a reconnect.
+	Forwards SSHForwards `yaml:"forwards,omitempty"`
+}
+
+// SSHForwards lists the port forwards of an SSH tunnel.
+type SSHForwards struct {
+	Local  []SSHForward `yaml:"local,omitempty"`  // listen here, connect from the server (ssh -L)
+	Remote []SSHForward `yaml:"remote,omitempty"` // listen on the server, connect from here (ssh -R)
+}
+
+// SSHForward is one port forward. A bare port in Listen binds localhost.
+type SSHForward struct {
+	Listen  string `yaml:"listen"`  // [host:]port
+	Connect string `yaml:"connect"` // host:port
+}
+
+// ListenAddr returns Listen as host:port.
+func (f *SSHForward) ListenAddr() string {
+	if _, err := strconv.Atoi(f.Listen); err == nil {
+		return net.JoinHostPort("localhost", f.Listen)
+	}
+	return f.Listen
+}
+
+// SSH tunnel modes.
+const (
+	SSHTunnelScript = "script"
+	SSHTunnelNative = "native"
+)
+
+// SSH remote helpers for script mode.
+const (
+	SSHHelperAuto   = "auto"
+	SSHHelperGo     = "go"
+	SSHHelperPython = "python"
+)
+
+// SSHAuth holds the credentials and host key settings for one SSH server.
+type SSHAuth struct {
+	KeyPath       string `yaml:"key_path,omitempty"`       // ~ is expanded
+	KeyPassphrase string `yaml:"key_passphrase,omitempty"` // for an encrypted key, asked for when empty; plaintext
+	CertPath      string `yaml:"cert_path,omitempty"`      // OpenSSH user certificate, default key_path-cert.pub
+	Password      string `yaml:"password,omitempty"`
+
+	// HostKeyFingerprints pins the server's host key (SHA256:... as shown
+	// by ssh-keygen -lf). When set, known_hosts is not consulted.
+	HostKeyFingerprints []string `yaml:"host_key_fingerprints,omitempty"`
+
+	// AuthMethods orders publickey (key_path, then ssh-agent keys),
+	// keyboard-interactive and password; unset methods are skipped.
+	AuthMethods    []string `yaml:"auth_methods,omitempty"`
+	IdentitiesOnly bool     `yaml:"identities_only,omitempty"` // do not offer ssh-agent keys
+}
+
+// SSHJumpHost is a bastion on the way to the SSH server.
+type SSHJumpHost struct {
+	Host    string `yaml:"host"`
+	Port    int    `yaml:"port,omitempty"` // 0 = 22
+	User    string `yaml:"user,omitempty"` // empty = the server's user
+	SSHAuth `yaml:",inline"`
 }
 
 // Routing configuration for split tunneling.
@@ -78,9 +255,23 @@ type DNS struct {
 
 // Interface configuration for the VPN adapter.
 type Interface struct {
-	Name   string `yaml:"name"`
-	MTU    int    `yaml:"mtu"`
-	Metric int    `yaml:"metric"`
+	Name     string `yaml:"name"`
+	MTU      MTU    `yaml:"mtu"` // number or "auto"
+	Metric   int    `yaml:"metric"`
+	ClampMSS bool   `yaml:"clamp_mss,omitempty"` // clamp TCP MSS of traffic leaving the adapter
+
+	StatsInterval int `yaml:"stats_interval,omitempty"` // seconds between traffic samples, 0 = use default (2)
+}
+
+// DefaultStatsInterval is how often traffic counters are sampled.
+const DefaultStatsInterval = 2 * time.Second
+
+// StatsPeriod returns the configured traffic sampling interval.
+func (i *Interface) StatsPeriod() time.Duration {
+	if i.StatsInterval <= 0 {
+		return DefaultStatsInterval
+	}
+	return time.Duration(i.StatsInterval) * time.Second
 }
 
 // KillSwitchConfig represents kill switch configuration.
@@ -90,6 +281,18 @@ type KillSwitchConfig struct {
 	AllowedProcesses []string `yaml:"allowed_processes,omitempty"`
 }
 
+// Proxy configures the local proxies exposed by userspace protocol modes.
+type Proxy struct {
+	SOCKSListen string `yaml:"socks_listen,omitempty"` // e.g. 127.0.0.1:1080, empty = disabled
+	HTTPListen  string `yaml:"http_listen,omitempty"`  // e.g. 127.0.0.1:8080, empty = disabled
+
+	// Username and Password, when set, are required from clients (SOCKS5
+	// username/password auth, HTTP Proxy-Authorization: Basic). Without
+	// them the proxies only listen on loopback addresses.
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+}
+
 // DefaultConfig returns a default configuration.
 func DefaultConfig() *Config {
 	return &Config{
@@ -131,5 +334,8 @@ func DefaultConfig() *Config {
 			Enabled:  false,
 			AllowLAN: true,
 		},
+		Proxy: Proxy{
+			SOCKSListen: "127.0.0.1:1080",
+		},
 	}
 }
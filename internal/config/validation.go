@@ -1,8 +1,15 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/user/vpn-client/internal/totp"
 )
 
 // Validate validates the configuration.
@@ -16,6 +23,13 @@ func (c *Config) Validate() error {
 		if err := c.WireGuard.Validate(); err != nil {
 			return fmt.Errorf("wireguard config: %w", err)
 		}
+	case ProtocolWireGuardUserspace:
+		if err := c.WireGuard.Validate(); err != nil {
+			return fmt.Errorf("wireguard config: %w", err)
+		}
+		if err := c.Proxy.Validate(); err != nil {
+			return fmt.Errorf("proxy config: %w", err)
+		}
 	case ProtocolOpenVPN:
 		if err := c.OpenVPN.Validate(); err != nil {
 			return fmt.Errorf("openvpn config: %w", err)
@@ -24,6 +38,13 @@ func (c *Config) Validate() error {
 		if err := c.SSH.Validate(); err != nil {
 			return fmt.Errorf("ssh config: %w", err)
 		}
+	case ProtocolSSHProxy:
+		if err := c.SSH.Validate(); err != nil {
+			return fmt.Errorf("ssh config: %w", err)
+		}
+		if err := c.Proxy.Validate(); err != nil {
+			return fmt.Errorf("proxy config: %w", err)
+		}
 	default:
 		return fmt.Errorf("unknown protocol: %s", c.Protocol)
 	}
@@ -53,6 +74,61 @@ func (w *WireGuard) Validate() error {
 	if w.Peer.Endpoint == "" {
 		return fmt.Errorf("peer.endpoint is required")
 	}
+	switch w.Transport {
+	case "", WireGuardTransportUDP:
+	case WireGuardTransportTCP:
+		if _, _, err := net.SplitHostPort(w.Relay); err != nil {
+			return fmt.Errorf("relay must be host:port for tcp transport")
+		}
+	case WireGuardTransportWebSocket:
+		u, err := url.Parse(w.Relay)
+		if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+			return fmt.Errorf("relay must be a ws:// or wss:// URL for websocket transport")
+		}
+	default:
+		return fmt.Errorf("unknown transport: %s", w.Transport)
+	}
+	if w.Obfuscated() {
+		if err := w.validateObfuscation(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateObfuscation applies the same limits as AmneziaWG.
+func (w *WireGuard) validateObfuscation() error {
+	if w.Jc < 0 || w.Jc > 128 {
+		return fmt.Errorf("jc must be between 0 and 128")
+	}
+	if w.Jc > 0 {
+		if w.Jmin < 0 || w.Jmax > 1280 || w.Jmin > w.Jmax {
+			return fmt.Errorf("jmin/jmax must satisfy 0 <= jmin <= jmax <= 1280")
+		}
+	}
+	if w.S1 < 0 || w.S1 > 1132 {
+		return fmt.Errorf("s1 must be between 0 and 1132")
+	}
+	if w.S2 < 0 || w.S2 > 1188 {
+		return fmt.Errorf("s2 must be between 0 and 1188")
+	}
+	// Initiation (148 bytes) and response (92 bytes) are told apart by size.
+	if w.S1+148 == w.S2+92 {
+		return fmt.Errorf("s1 + 56 must not equal s2")
+	}
+	h := []uint32{w.H1, w.H2, w.H3, w.H4}
+	for i, v := range h {
+		if v == 0 {
+			h[i] = uint32(i + 1)
+		}
+	}
+	for i := range h {
+		for j := i + 1; j < len(h); j++ {
+			if h[i] == h[j] {
+				return fmt.Errorf("h1-h4 must be distinct")
+			}
+		}
+	}
 	return nil
 }
 
@@ -61,6 +137,11 @@ func (o *OpenVPN) Validate() error {
 	if o.ConfigPath == "" {
 		return fmt.Errorf("config_path is required")
 	}
+	if o.TOTPSecret != "" {
+		if _, err := totp.DecodeSecret(o.TOTPSecret); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -75,8 +156,44 @@ func (s *SSH) Validate() error {
 	if s.User == "" {
 		return fmt.Errorf("user is required")
 	}
-	if s.KeyPath == "" && s.Password == "" {
-		return fmt.Errorf("key_path or password is required")
+	if err := s.SSHAuth.Validate(); err != nil {
+		return err
+	}
+	switch s.TunnelMode {
+	case "", SSHTunnelScript, SSHTunnelNative:
+	default:
+		return fmt.Errorf("tunnel_mode must be script or native")
+	}
+	switch s.RemoteHelper {
+	case "", SSHHelperAuto, SSHHelperGo, SSHHelperPython:
+	default:
+		return fmt.Errorf("remote_helper must be auto, go or python")
+	}
+	if s.TunUnit != "" && s.TunUnit != "any" {
+		if n, err := strconv.Atoi(s.TunUnit); err != nil || n < 0 {
+			return fmt.Errorf("tun_unit must be a number or any")
+		}
+	}
+	for i, hop := range s.JumpHosts {
+		if hop.Host == "" {
+			return fmt.Errorf("jump_hosts[%d]: host is required", i)
+		}
+		if hop.Port < 0 || hop.Port > 65535 {
+			return fmt.Errorf("jump_hosts[%d]: invalid port %d", i, hop.Port)
+		}
+		if err := hop.SSHAuth.Validate(); err != nil {
+			return fmt.Errorf("jump_hosts[%d]: %w", i, err)
+		}
+	}
+	for i, f := range s.Forwards.Local {
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("forwards.local[%d]: %w", i, err)
+		}
+	}
+	for i, f := range s.Forwards.Remote {
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("forwards.remote[%d]: %w", i, err)
+		}
 	}
 	if s.RemoteTunAddr != "" {
 		ip := s.RemoteTunAddr
@@ -107,6 +224,40 @@ func (s *SSH) Validate() error {
 	return nil
 }
 
+// Validate validates an SSH port forward.
+func (f *SSHForward) Validate() error {
+	if _, port, err := net.SplitHostPort(f.ListenAddr()); err != nil || !validPort(port) {
+		return fmt.Errorf("invalid listen address %q", f.Listen)
+	}
+	if host, port, err := net.SplitHostPort(f.Connect); err != nil || host == "" || !validPort(port) {
+		return fmt.Errorf("invalid connect address %q", f.Connect)
+	}
+	return nil
+}
+
+func validPort(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// Validate validates SSH credentials and host key settings.
+func (a *SSHAuth) Validate() error {
+	for _, m := range a.AuthMethods {
+		switch m {
+		case "publickey", "keyboard-interactive", "password":
+		default:
+			return fmt.Errorf("unknown auth_methods entry %q", m)
+		}
+	}
+	for _, fp := range a.HostKeyFingerprints {
+		hash, ok := strings.CutPrefix(fp, "SHA256:")
+		if raw, err := base64.RawStdEncoding.DecodeString(hash); !ok || err != nil || len(raw) != sha256.Size {
+			return fmt.Errorf("invalid host_key_fingerprints entry %q: expected SHA256:<base64>", fp)
+		}
+	}
+	return nil
+}
+
 // Validate validates routing configuration.
 func (r *Routing) Validate() error {
 	for _, ip := range r.IncludeIPs {
@@ -129,11 +280,49 @@ func (i *Interface) Validate() error {
 	if i.Name == "" {
 		return fmt.Errorf("name is required")
 	}
-	if i.MTU < 576 || i.MTU > 65535 {
+	if !i.MTU.IsAuto() && (i.MTU < 576 || i.MTU > 65535) {
 		return fmt.Errorf("mtu must be between 576 and 65535")
 	}
 	if i.Metric < 1 || i.Metric > 9999 {
 		return fmt.Errorf("metric must be between 1 and 9999")
 	}
+	if i.StatsInterval < 0 {
+		return fmt.Errorf("stats_interval must not be negative")
+	}
+	return nil
+}
+
+// Validate validates local proxy configuration.
+func (p *Proxy) Validate() error {
+	if p.SOCKSListen == "" && p.HTTPListen == "" {
+		return fmt.Errorf("socks_listen or http_listen is required")
+	}
+	for _, addr := range []string{p.SOCKSListen, p.HTTPListen} {
+		if addr == "" {
+			continue
+		}
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return fmt.Errorf("invalid listen address %s: %w", addr, err)
+		}
+		if p.Username == "" && !isLoopback(host) {
+			return fmt.Errorf("listen address %s is not a loopback address; set username and password to expose the proxy", addr)
+		}
+	}
+	if (p.Username == "") != (p.Password == "") {
+		return fmt.Errorf("username and password must be set together")
+	}
+	if len(p.Username) > 255 || len(p.Password) > 255 {
+		return fmt.Errorf("username and password must not exceed 255 bytes")
+	}
 	return nil
 }
+
+// isLoopback reports whether a listen host only accepts local connections.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -12,6 +12,9 @@ import (
 	"strings"
 )
 
+// resolveProcessNames leaves process names empty: /proc/net carries no owning process.
+func resolveProcessNames(conns []Connection) {}
+
 func getSystemConnections() ([]Connection, error) {
 	var conns []Connection
 
@@ -15,6 +15,9 @@ func (m *Monitor) poll() {
 	// Resolve process names
 	resolveProcessNames(conns)
 
+	// Connections relayed by the local proxies
+	conns = append(conns, ProxiedConnections()...)
+
 	// Resolve domains in background
 	m.resolveDomainsAsync(conns)
 
@@ -42,6 +45,10 @@ func (m *Monitor) detectConnectionChanges(current []Connection) {
 			key := c.Key()
 			currentEstablished[key] = true
 
+			if c.Domain == "" {
+				c.Domain = m.lookupCachedDomain(c.RemoteAddr.Addr().String())
+			}
+
 			m.mu.Lock()
 			if !m.prevEstablished[key] {
 				// New ESTABLISHED connection - add to journal
@@ -50,9 +57,9 @@ func (m *Monitor) detectConnectionChanges(current []Connection) {
 					Protocol:    c.Protocol,
 					LocalAddr:   c.LocalAddr,
 					RemoteAddr:  c.RemoteAddr,
-					Domain:      m.lookupCachedDomain(c.RemoteAddr.Addr().String()),
+					Domain:      c.Domain,
 					Reason:      "ESTABLISHED",
-					RemoteIP:    c.RemoteAddr.Addr().String(),
+					RemoteIP:    remoteIP(c),
 					ProcessName: c.ProcessName,
 				})
 				m.prevEstablished[key] = true
@@ -135,6 +142,15 @@ func (m *Monitor) detectConnectionChanges(current []Connection) {
 	m.mu.Unlock()
 }
 
+// remoteIP returns the remote address for display, or the host name of a
+// proxied connection to a name.
+func remoteIP(c Connection) string {
+	if !c.RemoteAddr.IsValid() {
+		return c.Domain
+	}
+	return c.RemoteAddr.Addr().String()
+}
+
 func (m *Monitor) addJournalEntry(entry JournalEntry) {
 	remoteIP := entry.RemoteAddr.Addr()
 
@@ -159,6 +175,7 @@ func (m *Monitor) addJournalEntry(entry JournalEntry) {
 	for i := len(m.journal) - 1; i >= 0 && i >= len(m.journal)-20; i-- {
 		e := m.journal[i]
 		if e.RemoteAddr == entry.RemoteAddr &&
+			e.RemoteIP == entry.RemoteIP &&
 			e.Protocol == entry.Protocol &&
 			entry.Timestamp.Sub(e.Timestamp) < 30*time.Second {
 			return // Skip duplicate
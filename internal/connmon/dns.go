@@ -8,6 +8,9 @@ import (
 func (m *Monitor) resolveDomainsAsync(conns []Connection) {
 	seen := make(map[string]bool)
 	for i := range conns {
+		if !conns[i].RemoteAddr.IsValid() {
+			continue // proxied connection to a host name
+		}
 		ip := conns[i].RemoteAddr.Addr().String()
 		if ip == "" || ip == "0.0.0.0" || ip == "::" {
 			continue
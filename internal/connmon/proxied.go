@@ -0,0 +1,109 @@
+package connmon
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Connections relayed by the local proxies reach the server inside the
+// tunnel, so the system connection table only shows the client side on
+// loopback. The proxies register them here and the monitor lists them
+// next to the system connections.
+var proxied = struct {
+	sync.Mutex
+	next  uint64
+	conns map[uint64]*proxiedConn
+}{conns: make(map[uint64]*proxiedConn)}
+
+// proxiedConn counts the traffic of a relayed connection and unregisters
+// it when closed.
+type proxiedConn struct {
+	net.Conn
+	id        uint64
+	via       string
+	target    string
+	started   time.Time
+	sent      atomic.Uint64
+	received  atomic.Uint64
+	closeOnce sync.Once
+}
+
+// TrackProxied registers a connection a proxy opened to target on behalf
+// of a local client and returns it wrapped for traffic accounting. via
+// names the proxy, e.g. "ssh-proxy".
+func TrackProxied(via, target string, c net.Conn) net.Conn {
+	p := &proxiedConn{Conn: c, via: via, target: target, started: time.Now()}
+
+	proxied.Lock()
+	proxied.next++
+	p.id = proxied.next
+	proxied.conns[p.id] = p
+	proxied.Unlock()
+	return p
+}
+
+func (p *proxiedConn) Read(b []byte) (int, error) {
+	n, err := p.Conn.Read(b)
+	p.received.Add(uint64(n))
+	return n, err
+}
+
+func (p *proxiedConn) Write(b []byte) (int, error) {
+	n, err := p.Conn.Write(b)
+	p.sent.Add(uint64(n))
+	return n, err
+}
+
+func (p *proxiedConn) Close() error {
+	p.closeOnce.Do(func() {
+		proxied.Lock()
+		delete(proxied.conns, p.id)
+		proxied.Unlock()
+	})
+	return p.Conn.Close()
+}
+
+// CloseWrite half-closes the connection when the underlying one supports
+// it, so relays keep working through the wrapper.
+func (p *proxiedConn) CloseWrite() error {
+	if cw, ok := p.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return p.Close()
+}
+
+// ProxiedConnections returns the open proxied connections.
+func ProxiedConnections() []Connection {
+	proxied.Lock()
+	defer proxied.Unlock()
+
+	conns := make([]Connection, 0, len(proxied.conns))
+	for _, p := range proxied.conns {
+		c := Connection{
+			Protocol:      "TCP",
+			State:         StateEstablished,
+			ProcessName:   p.via,
+			Via:           p.via,
+			Started:       p.started,
+			BytesSent:     p.sent.Load(),
+			BytesReceived: p.received.Load(),
+			id:            p.id,
+		}
+		if ap, err := netip.ParseAddrPort(p.target); err == nil {
+			c.RemoteAddr = ap
+		} else if host, _, err := net.SplitHostPort(p.target); err == nil {
+			c.Domain = host
+		}
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// proxiedKey identifies a proxied connection, which has no local address.
+func (c *Connection) proxiedKey() string {
+	return fmt.Sprintf("%s|%d", c.Via, c.id)
+}
@@ -62,10 +62,20 @@ type Connection struct {
 	PID         uint32
 	ProcessName string // Executable name resolved from PID
 	Domain      string // Resolved domain name (reverse DNS)
+
+	// Set for connections relayed by a local proxy (see TrackProxied).
+	Via           string // proxy that relayed it, empty for system connections
+	Started       time.Time
+	BytesSent     uint64
+	BytesReceived uint64
+	id            uint64
 }
 
 // Key returns a unique key for this connection.
 func (c *Connection) Key() string {
+	if c.Via != "" {
+		return c.proxiedKey()
+	}
 	return fmt.Sprintf("%s|%s|%s", c.Protocol, c.LocalAddr, c.RemoteAddr)
 }
 
@@ -2,6 +2,7 @@ package core
 
 import (
 	"fmt"
+	"net"
 	"os/exec"
 	"time"
 
@@ -47,26 +48,45 @@ func (s *Service) Connect() error {
 	case config.ProtocolWireGuard:
 		logger.Info("Creating WireGuard tunnel")
 		tunnel = wireguard.New(&cfg.WireGuard, &cfg.Interface)
+	case config.ProtocolWireGuardUserspace:
+		logger.Info("Creating userspace WireGuard tunnel")
+		tunnel = wireguard.NewUserspace(&cfg.WireGuard, &cfg.Interface, &cfg.Proxy)
 	case config.ProtocolOpenVPN:
 		logger.Info("Creating OpenVPN tunnel")
 		tunnel = openvpn.New(&cfg.OpenVPN, &cfg.Interface)
 	case config.ProtocolSSH:
 		logger.Info("Creating SSH tunnel")
 		tunnel = ssh.New(&cfg.SSH, &cfg.Interface)
+	case config.ProtocolSSHProxy:
+		logger.Info("Creating SSH proxy tunnel")
+		tunnel = ssh.NewProxy(&cfg.SSH, &cfg.Interface, &cfg.Proxy)
 	default:
 		logger.Error("Unknown protocol: " + string(cfg.Protocol))
 		s.setError(fmt.Errorf("unknown protocol: %s", cfg.Protocol))
 		return s.lastError
 	}
 
+	if p, ok := tunnel.(protocols.Prompter); ok {
+		p.SetPromptFunc(s.prompt)
+	}
+
+	// Userspace protocols run unprivileged and must not touch routing,
+	// DNS or the firewall.
+	userspace := !cfg.Protocol.RequiresAdmin()
+
 	// Enable kill switch before connecting
-	if cfg.KillSwitch.Enabled {
+	if cfg.KillSwitch.Enabled && !userspace {
 		logger.Info("Enabling kill switch...")
-		serverIP := s.getServerIP(cfg)
+		var serverIP string
+		var extraServers []string
+		if ips := s.getServerIPs(cfg); len(ips) > 0 {
+			serverIP, extraServers = ips[0], ips[1:]
+		}
 		if err := s.killSwitch.Enable(&killswitch.Config{
 			Enabled:          true,
 			AllowLAN:         cfg.KillSwitch.AllowLAN,
 			VPNServerIP:      serverIP,
+			ExtraServerIPs:   extraServers,
 			VPNInterface:     cfg.Interface.Name,
 			AllowedProcesses: cfg.KillSwitch.AllowedProcesses,
 		}); err != nil {
@@ -81,7 +101,7 @@ func (s *Service) Connect() error {
 	logger.Info("Starting tunnel...")
 	if err := tunnel.Start(s.ctx); err != nil {
 		logger.Error("Failed to start tunnel: " + err.Error())
-		if cfg.KillSwitch.Enabled {
+		if cfg.KillSwitch.Enabled && !userspace {
 			s.killSwitch.Disable()
 		}
 		s.setError(err)
@@ -90,6 +110,7 @@ func (s *Service) Connect() error {
 
 	s.mu.Lock()
 	s.tunnel = tunnel
+	s.tunnelCfg = cfg
 	s.mu.Unlock()
 
 	// Wait for tunnel to connect
@@ -116,6 +137,11 @@ func (s *Service) Connect() error {
 		return s.lastError
 	}
 
+	if userspace {
+		s.finishConnect(cfg, tunnel)
+		return nil
+	}
+
 	logger.Info("Tunnel connected, configuring routing...")
 
 	// Initialize routing
@@ -136,9 +162,24 @@ func (s *Service) Connect() error {
 		return err
 	}
 
-	// Ensure VPN server is routed via original gateway
-	if err := s.routing.EnsureVPNServerRoute(tunnel.ServerIP()); err != nil {
-		// Non-fatal, log and continue
+	// Full tunnel WireGuard on Linux uses wg-quick style fwmark policy
+	// routing, which keeps working when the default gateway changes.
+	policyRouting := false
+	if cfg.Routing.DefaultRoute && cfg.Protocol == config.ProtocolWireGuard && routing.PolicyRoutingSupported {
+		if err := s.routing.EnablePolicyRouting(cfg.Interface.Name, cfg.WireGuard.Mark()); err != nil {
+			logger.Warning("Policy routing failed, falling back to default routes: " + err.Error())
+		} else {
+			policyRouting = true
+			logger.Info(fmt.Sprintf("Policy routing enabled (fwmark %d)", cfg.WireGuard.Mark()))
+		}
+	}
+
+	// Ensure VPN server is routed via original gateway; marked packets
+	// already bypass the tunnel under policy routing.
+	if !policyRouting {
+		if err := s.routing.EnsureVPNServerRoute(tunnel.ServerIP()); err != nil {
+			// Non-fatal, log and continue
+		}
 	}
 
 	// Remove any existing routes to tunnel gateway
@@ -150,8 +191,20 @@ func (s *Service) Connect() error {
 		logger.Warning("Failed to add tunnel gateway route: " + err.Error())
 	}
 
+	// Routes and DNS pushed by the server add to the local configuration
+	var pushed protocols.PushedConfig
+	if pr, ok := tunnel.(protocols.PushReceiver); ok {
+		pushed = pr.Pushed()
+		if pushed.DefaultRoute || len(pushed.Routes) > 0 || len(pushed.DNSServers) > 0 {
+			logger.Info(fmt.Sprintf("Server pushed %d routes, redirect-gateway=%v, DNS %v",
+				len(pushed.Routes), pushed.DefaultRoute, pushed.DNSServers))
+		}
+	}
+
 	// Check if default route is enabled
-	if cfg.Routing.DefaultRoute {
+	if policyRouting {
+		logger.Info("Default route enabled: routing all traffic through VPN table")
+	} else if cfg.Routing.DefaultRoute || pushed.DefaultRoute {
 		// Route all traffic through VPN using 0.0.0.0/1 and 128.0.0.0/1
 		// This approach is more reliable on Windows than single 0.0.0.0/0
 		logger.Info("Default route enabled: routing all traffic through VPN")
@@ -171,6 +224,19 @@ func (s *Service) Connect() error {
 		}
 	}
 
+	for _, prefix := range pushed.Routes {
+		if err := s.routing.AddRoute(prefix.String(), "pushed"); err != nil {
+			logger.Warning("Failed to add pushed route " + prefix.String() + ": " + err.Error())
+		}
+	}
+	if pushed.DefaultRoute6 {
+		for _, prefix := range []string{"::/1", "8000::/1"} {
+			if err := s.routing.AddRoute(prefix, "default"); err != nil {
+				logger.Warning("Failed to add default route " + prefix + ": " + err.Error())
+			}
+		}
+	}
+
 	// Fetch routes from local routes.txt file
 	logger.Info("Reading local routes file")
 	localRoutes, err := routing.ReadLocalRoutesFile()
@@ -197,13 +263,21 @@ func (s *Service) Connect() error {
 		s.routing.StartDomainResolver(cfg.Routing.IncludeDomains, interval)
 	}
 
-	// Configure DNS
-	if len(cfg.DNS.Servers) > 0 {
-		logger.Info("Configuring DNS servers: " + fmt.Sprintf("%v", cfg.DNS.Servers))
+	// Configure DNS; pushed servers replace the configured ones
+	dnsServers := cfg.DNS.Servers
+	if len(pushed.DNSServers) > 0 {
+		dnsServers = make([]string, len(pushed.DNSServers))
+		for i, addr := range pushed.DNSServers {
+			dnsServers[i] = addr.String()
+		}
+	}
+	dnsDomains := append(append([]string{}, pushed.DNSDomains...), cfg.DNS.Domains...)
+	if len(dnsServers) > 0 {
+		logger.Info("Configuring DNS servers: " + fmt.Sprintf("%v", dnsServers))
 		if err := s.dns.Configure(&dns.Config{
-			Servers:       cfg.DNS.Servers,
+			Servers:       dnsServers,
 			SplitDNS:      cfg.DNS.SplitDNS,
-			Domains:       cfg.DNS.Domains,
+			Domains:       dnsDomains,
 			InterfaceName: cfg.Interface.Name,
 		}); err != nil {
 			logger.Warning("DNS configuration failed: " + err.Error())
@@ -220,6 +294,30 @@ func (s *Service) Connect() error {
 		s.killSwitch.UpdateVPNInterface(cfg.Interface.Name)
 	}
 
+	// Clamp TCP MSS for networks that black-hole ICMP
+	if cfg.Interface.ClampMSS {
+		mtu := tunnel.MTU()
+		if mtu <= 0 {
+			// Not chosen by the tunnel; use the adapter's
+			if iface, err := net.InterfaceByName(cfg.Interface.Name); err == nil {
+				mtu = iface.MTU
+			}
+		}
+		if mtu <= 0 {
+			logger.Warning("MSS clamping skipped: MTU of %s is unknown", cfg.Interface.Name)
+		} else if err := s.killSwitch.EnableMSSClamp(cfg.Interface.Name, mtu); err != nil {
+			logger.Warning("MSS clamping failed: " + err.Error())
+		} else {
+			logger.Info("TCP MSS clamping enabled")
+		}
+	}
+
+	s.finishConnect(cfg, tunnel)
+	return nil
+}
+
+// finishConnect marks the service connected and starts monitoring the tunnel.
+func (s *Service) finishConnect(cfg *config.Config, tunnel protocols.Tunnel) {
 	s.mu.Lock()
 	s.state = StateConnected
 	s.connectedAt = time.Now()
@@ -231,13 +329,18 @@ func (s *Service) Connect() error {
 
 	s.broadcastStatus()
 
+	// Follow network changes to keep mtu: auto accurate
+	if prober, ok := tunnel.(protocols.MTUProber); ok && cfg.Interface.MTU.IsAuto() {
+		s.startNetworkWatch(prober, cfg.Interface.Name)
+	}
+
+	s.startStatsSampler(tunnel, cfg.Interface.StatsPeriod())
+
 	// Start monitoring tunnel state changes
 	go func() {
 		defer logger.Recover("monitorTunnel")
 		s.monitorTunnel()
 	}()
-
-	return nil
 }
 
 // Disconnect terminates the VPN connection.
@@ -271,6 +374,7 @@ func (s *Service) Disconnect() error {
 	s.mu.Lock()
 	s.state = StateDisconnected
 	s.tunnel = nil
+	s.tunnelCfg = nil
 	s.connectedAt = time.Time{}
 	s.mu.Unlock()
 
@@ -280,16 +384,34 @@ func (s *Service) Disconnect() error {
 	return nil
 }
 
-// disconnectInternal performs the actual disconnection cleanup.
+// disconnectInternal performs the actual disconnection cleanup. It undoes
+// what the connection set up, so it follows the configuration the tunnel
+// was started with rather than settings changed since.
 func (s *Service) disconnectInternal() {
+	cfg := s.tunnelCfg
+	if cfg == nil {
+		cfg = s.configManager.Get()
+	}
+
+	s.stopNetworkWatch()
+	s.stopStatsSampler()
+
+	// Userspace protocols made no system changes
+	if !cfg.Protocol.RequiresAdmin() {
+		if s.tunnel != nil {
+			logger.Info("Stopping tunnel...")
+			s.tunnel.Stop()
+		}
+		return
+	}
+
 	// Stop domain resolver
 	s.routing.StopDomainResolver()
 
 	// Remove all routes
 	logger.Info("Removing VPN routes...")
 	s.routing.RemoveAllRoutes()
-
-	cfg := s.configManager.Get()
+	s.routing.DisablePolicyRouting()
 
 	// Remove VPN server route
 	if s.tunnel != nil {
@@ -306,6 +428,9 @@ func (s *Service) disconnectInternal() {
 		s.killSwitch.Disable()
 	}
 
+	// Remove MSS clamping
+	s.killSwitch.DisableMSSClamp()
+
 	// Stop tunnel
 	if s.tunnel != nil {
 		logger.Info("Stopping tunnel...")
@@ -315,12 +440,13 @@ func (s *Service) disconnectInternal() {
 
 // cleanupOnError performs cleanup after connection error.
 func (s *Service) cleanupOnError(cfg *config.Config) {
-	if cfg.KillSwitch.Enabled {
+	if cfg.KillSwitch.Enabled && cfg.Protocol.RequiresAdmin() {
 		s.killSwitch.Disable()
 	}
 	if s.tunnel != nil {
 		s.tunnel.Stop()
 		s.tunnel = nil
+		s.tunnelCfg = nil
 	}
 }
 
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+)
+
+// networkPollInterval is how often local addresses are checked for changes.
+const networkPollInterval = 5 * time.Second
+
+// startNetworkWatch re-probes the MTU of tunnels using mtu: auto whenever
+// the host's network changes (Wi-Fi roaming, cable plugged in, PPPoE up).
+func (s *Service) startNetworkWatch(prober protocols.MTUProber, vpnIface string) {
+	ctx, cancel := context.WithCancel(s.ctx)
+
+	s.mu.Lock()
+	if s.netWatchCancel != nil {
+		s.netWatchCancel()
+	}
+	s.netWatchCancel = cancel
+	s.mu.Unlock()
+
+	go func() {
+		defer logger.Recover("networkWatch")
+
+		last := networkFingerprint(vpnIface)
+		ticker := time.NewTicker(networkPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			s.mu.RLock()
+			connected := s.state == StateConnected
+			clampMSS := s.tunnelCfg != nil && s.tunnelCfg.Interface.ClampMSS
+			s.mu.RUnlock()
+			if !connected {
+				continue
+			}
+
+			current := networkFingerprint(vpnIface)
+			if current == last {
+				continue
+			}
+			last = current
+
+			logger.Info("Network change detected, re-probing MTU")
+			mtu, err := prober.ReprobeMTU()
+			if err != nil {
+				logger.Warning("MTU re-probe failed: " + err.Error())
+				continue
+			}
+			logger.Info("Tunnel MTU is %d", mtu)
+			// As installed at connect, whatever the settings say now
+			if clampMSS {
+				if err := s.killSwitch.EnableMSSClamp(vpnIface, mtu); err != nil {
+					logger.Warning("MSS clamping failed: " + err.Error())
+				}
+			}
+			s.broadcastStatus()
+		}
+	}()
+}
+
+// stopNetworkWatch stops the watcher started by startNetworkWatch.
+func (s *Service) stopNetworkWatch() {
+	s.mu.Lock()
+	cancel := s.netWatchCancel
+	s.netWatchCancel = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// networkFingerprint summarizes the addresses of all active interfaces
+// except the VPN adapter.
+func networkFingerprint(vpnIface string) string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+
+	var parts []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Name == vpnIface {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			parts = append(parts, iface.Name+"="+addr.String())
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
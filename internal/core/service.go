@@ -35,6 +35,10 @@ type StatusPayload struct {
 	ConnectedAt   time.Time
 	BytesSent     uint64
 	BytesReceived uint64
+	RateSent      uint64                  // bytes per second
+	RateReceived  uint64                  // bytes per second
+	MTU           int                     // interface MTU, 0 if unknown
+	Forwards      []protocols.PortForward // SSH port forwards, if any
 	Error         string
 }
 
@@ -47,6 +51,7 @@ type Service struct {
 	state          State
 	configManager  *config.Manager
 	tunnel         protocols.Tunnel
+	tunnelCfg      *config.Config // configuration the tunnel was started with
 	routing        *routing.Manager
 	dns            *dns.Manager
 	killSwitch     *killswitch.KillSwitch
@@ -56,6 +61,10 @@ type Service struct {
 	lastError      error
 	wasConnected   bool // For resume after sleep
 	statusListener StatusListener
+	promptHandler  protocols.PromptFunc // asks the user for OTPs and challenge responses
+	netWatchCancel context.CancelFunc   // stops the mtu: auto network watcher
+	statsCancel    context.CancelFunc   // stops the traffic sampler
+	rates          trafficRates
 }
 
 // NewService creates a new VPN service.
@@ -95,6 +104,27 @@ func (s *Service) SetStatusListener(listener StatusListener) {
 	s.statusListener = listener
 }
 
+// SetPromptHandler sets the callback tunnels use to ask the user for input
+// while connecting, e.g. one-time passwords.
+func (s *Service) SetPromptHandler(handler protocols.PromptFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.promptHandler = handler
+}
+
+// prompt forwards a tunnel prompt to the prompt handler, if any.
+func (s *Service) prompt(p protocols.Prompt) (string, bool) {
+	s.mu.RLock()
+	handler := s.promptHandler
+	s.mu.RUnlock()
+
+	if handler == nil {
+		logger.Warning("Cannot ask for input, no prompt handler: %s", p.Message)
+		return "", false
+	}
+	return handler(p)
+}
+
 // Start starts the VPN service.
 func (s *Service) Start() error {
 	logger.Info("Starting VPN service...")
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+)
+
+// trafficRates holds transfer rates derived from two counter samples.
+type trafficRates struct {
+	sent     uint64 // bytes per second
+	received uint64
+
+	last     protocols.Stats
+	lastTime time.Time
+}
+
+// update derives rates from a new sample. Counters that went backwards
+// (the tunnel restarted) start a new baseline.
+func (r *trafficRates) update(stats protocols.Stats, now time.Time) {
+	if !r.lastTime.IsZero() && stats.BytesSent >= r.last.BytesSent && stats.BytesReceived >= r.last.BytesReceived {
+		if elapsed := now.Sub(r.lastTime).Seconds(); elapsed > 0 {
+			r.sent = uint64(float64(stats.BytesSent-r.last.BytesSent) / elapsed)
+			r.received = uint64(float64(stats.BytesReceived-r.last.BytesReceived) / elapsed)
+		}
+	} else {
+		r.sent, r.received = 0, 0
+	}
+	r.last, r.lastTime = stats, now
+}
+
+// startStatsSampler samples the tunnel's traffic counters every interval,
+// derives rates and broadcasts the status so the UI stays current.
+func (s *Service) startStatsSampler(tunnel protocols.Tunnel, interval time.Duration) {
+	ctx, cancel := context.WithCancel(s.ctx)
+
+	s.mu.Lock()
+	if s.statsCancel != nil {
+		s.statsCancel()
+	}
+	s.statsCancel = cancel
+	s.rates = trafficRates{}
+	s.mu.Unlock()
+
+	go func() {
+		defer logger.Recover("statsSampler")
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			stats := tunnel.Stats()
+
+			s.mu.Lock()
+			if s.tunnel != tunnel {
+				s.mu.Unlock()
+				return
+			}
+			s.rates.update(stats, time.Now())
+			connected := s.state == StateConnected
+			s.mu.Unlock()
+
+			if connected {
+				s.broadcastStatus()
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stopStatsSampler stops the sampler started by startStatsSampler.
+func (s *Service) stopStatsSampler() {
+	s.mu.Lock()
+	cancel := s.statsCancel
+	s.statsCancel = nil
+	s.rates = trafficRates{}
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
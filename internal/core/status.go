@@ -1,5 +1,9 @@
 package core
 
+import (
+	"github.com/user/vpn-client/internal/protocols"
+)
+
 // GetStatusPayload returns the current status.
 func (s *Service) GetStatusPayload() *StatusPayload {
 	s.mu.RLock()
@@ -10,14 +14,24 @@ func (s *Service) GetStatusPayload() *StatusPayload {
 	}
 
 	if s.tunnel != nil {
-		cfg := s.configManager.Get()
+		cfg := s.tunnelCfg
+		if cfg == nil {
+			cfg = s.configManager.Get()
+		}
 		status.Protocol = string(cfg.Protocol)
 		status.ServerAddress = s.tunnel.ServerIP()
 		status.LocalIP = s.tunnel.LocalIP().String()
+		status.MTU = s.tunnel.MTU()
 
 		stats := s.tunnel.Stats()
 		status.BytesSent = stats.BytesSent
 		status.BytesReceived = stats.BytesReceived
+		status.RateSent = s.rates.sent
+		status.RateReceived = s.rates.received
+
+		if f, ok := s.tunnel.(protocols.PortForwarder); ok {
+			status.Forwards = f.PortForwards()
+		}
 	}
 
 	if !s.connectedAt.IsZero() {
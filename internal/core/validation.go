@@ -5,12 +5,14 @@ import (
 	"net/netip"
 
 	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols/openvpn"
 )
 
 // validateConfig validates the configuration before connecting.
 func (s *Service) validateConfig(cfg *config.Config) error {
 	switch cfg.Protocol {
-	case config.ProtocolWireGuard:
+	case config.ProtocolWireGuard, config.ProtocolWireGuardUserspace:
 		if cfg.WireGuard.PrivateKey == "" {
 			return fmt.Errorf("WireGuard: private key is required")
 		}
@@ -27,21 +29,35 @@ func (s *Service) validateConfig(cfg *config.Config) error {
 		if _, err := netip.ParsePrefix(cfg.WireGuard.Address); err != nil {
 			return fmt.Errorf("WireGuard: invalid address format '%s' (expected CIDR like 10.0.0.2/24)", cfg.WireGuard.Address)
 		}
+		if cfg.Protocol == config.ProtocolWireGuardUserspace {
+			if cfg.Proxy.SOCKSListen == "" && cfg.Proxy.HTTPListen == "" {
+				return fmt.Errorf("WireGuard: userspace mode requires proxy.socks_listen or proxy.http_listen")
+			}
+		}
 
 	case config.ProtocolOpenVPN:
 		if cfg.OpenVPN.ConfigPath == "" {
 			return fmt.Errorf("OpenVPN: config file path is required")
 		}
+		profile, err := openvpn.ParseProfile(cfg.OpenVPN.ConfigPath)
+		if err != nil {
+			return fmt.Errorf("OpenVPN: %w", err)
+		}
+		if err := profile.Validate(); err != nil {
+			return fmt.Errorf("OpenVPN: %w", err)
+		}
 
-	case config.ProtocolSSH:
+	case config.ProtocolSSH, config.ProtocolSSHProxy:
 		if cfg.SSH.Host == "" {
 			return fmt.Errorf("SSH: host is required")
 		}
 		if cfg.SSH.User == "" {
 			return fmt.Errorf("SSH: user is required")
 		}
-		if cfg.SSH.KeyPath == "" && cfg.SSH.Password == "" {
-			return fmt.Errorf("SSH: key_path or password is required")
+		if cfg.Protocol == config.ProtocolSSHProxy {
+			if cfg.Proxy.SOCKSListen == "" && cfg.Proxy.HTTPListen == "" {
+				return fmt.Errorf("SSH: proxy mode requires proxy.socks_listen or proxy.http_listen")
+			}
 		}
 
 	default:
@@ -51,21 +67,29 @@ func (s *Service) validateConfig(cfg *config.Config) error {
 	return nil
 }
 
-// getServerIP extracts server IP from configuration.
-func (s *Service) getServerIP(cfg *config.Config) string {
+// getServerIPs returns the server addresses the kill switch must allow.
+func (s *Service) getServerIPs(cfg *config.Config) []string {
 	switch cfg.Protocol {
-	case config.ProtocolWireGuard:
-		// Extract host from endpoint
-		endpoint := cfg.WireGuard.Peer.Endpoint
+	case config.ProtocolWireGuard, config.ProtocolWireGuardUserspace:
+		// Extract host from endpoint (or relay)
+		endpoint := cfg.WireGuard.DialAddress()
 		host, _, _ := splitHostPort(endpoint)
-		return host
+		return []string{host}
 	case config.ProtocolOpenVPN:
-		// Parse from config file if needed
-		return ""
-	case config.ProtocolSSH:
-		return cfg.SSH.Host
+		// All remotes of the profile, so OpenVPN can fail over
+		ips, err := openvpn.ServerIPs(s.ctx, cfg.OpenVPN.ConfigPath)
+		if err != nil {
+			logger.Warning("Failed to resolve OpenVPN servers: " + err.Error())
+		}
+		return ips
+	case config.ProtocolSSH, config.ProtocolSSHProxy:
+		// Only the first jump host is reached directly
+		if len(cfg.SSH.JumpHosts) > 0 {
+			return []string{cfg.SSH.JumpHosts[0].Host}
+		}
+		return []string{cfg.SSH.Host}
 	}
-	return ""
+	return nil
 }
 
 // splitHostPort splits host:port string.
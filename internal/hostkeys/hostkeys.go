@@ -0,0 +1,190 @@
+// Package hostkeys verifies SSH server host keys against known_hosts files
+// and pinned fingerprints, asking the user about servers seen for the first
+// time (trust on first use).
+package hostkeys
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+)
+
+var (
+	// ErrChanged means the server presented a key other than the known one.
+	ErrChanged = errors.New("host key changed")
+
+	// ErrUnknown means the server is not known and was not trusted.
+	ErrUnknown = errors.New("host key unknown")
+)
+
+// Options configures host key verification.
+type Options struct {
+	// Pinned SHA256 fingerprints. When set, only these keys are accepted
+	// and known_hosts is not consulted.
+	Pinned []string
+
+	// Prompt asks whether to trust an unknown server. nil rejects it.
+	Prompt protocols.PromptFunc
+}
+
+// UserKnownHostsPath returns the user's OpenSSH known_hosts file.
+func UserKnownHostsPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
+
+// AppKnownHostsPath returns the known_hosts file kept by the client next to
+// its configuration. Keys trusted on first use are added here.
+func AppKnownHostsPath() string {
+	return filepath.Join(filepath.Dir(config.GetConfigPath()), "known_hosts")
+}
+
+// Callback returns an ssh.HostKeyCallback implementing opts.
+func Callback(opts Options) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		fp := ssh.FingerprintSHA256(key)
+
+		if len(opts.Pinned) > 0 {
+			for _, p := range opts.Pinned {
+				if p == fp {
+					return nil
+				}
+			}
+			return fmt.Errorf("%w: %s presented %s %s, which is not a pinned fingerprint",
+				ErrChanged, hostname, key.Type(), fp)
+		}
+
+		known, err := load()
+		if err != nil {
+			return err
+		}
+		if known != nil {
+			err := known(hostname, remote, key)
+			if err == nil {
+				return nil
+			}
+			var keyErr *knownhosts.KeyError
+			if !errors.As(err, &keyErr) {
+				return err // revoked key or malformed address
+			}
+			if len(keyErr.Want) > 0 {
+				want := keyErr.Want[0]
+				return fmt.Errorf("%w: %s presented %s %s, but %s:%d has %s %s; remove that line if the change is expected",
+					ErrChanged, hostname, key.Type(), fp,
+					want.Filename, want.Line, want.Key.Type(), ssh.FingerprintSHA256(want.Key))
+			}
+		}
+
+		return trustOnFirstUse(hostname, key, opts.Prompt)
+	}
+}
+
+// Algorithms returns the host key algorithms known for addr, preferred in
+// the handshake so the server presents a key that can be verified. nil if
+// the server is unknown.
+func Algorithms(addr string) []string {
+	known, err := load()
+	if err != nil || known == nil {
+		return nil
+	}
+	var keyErr *knownhosts.KeyError
+	if !errors.As(known(addr, &net.TCPAddr{IP: net.IPv4zero}, probeKey{}), &keyErr) {
+		return nil
+	}
+
+	var algos []string
+	seen := make(map[string]bool)
+	for _, k := range keyErr.Want {
+		types := []string{k.Key.Type()}
+		if types[0] == ssh.KeyAlgoRSA {
+			types = []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+		}
+		for _, t := range types {
+			if !seen[t] {
+				seen[t] = true
+				algos = append(algos, t)
+			}
+		}
+	}
+	return algos
+}
+
+// load builds a callback over the known_hosts files that exist. It is read
+// on every connection so keys trusted meanwhile are picked up.
+func load() (ssh.HostKeyCallback, error) {
+	var files []string
+	for _, path := range []string{UserKnownHostsPath(), AppKnownHostsPath()} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	cb, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+	return cb, nil
+}
+
+// trustOnFirstUse asks the user about an unknown key and remembers it in
+// the client's known_hosts file.
+func trustOnFirstUse(hostname string, key ssh.PublicKey, prompt protocols.PromptFunc) error {
+	fp := ssh.FingerprintSHA256(key)
+	if prompt == nil {
+		return fmt.Errorf("%w: %s presented %s %s; add it to known_hosts or pin its fingerprint",
+			ErrUnknown, hostname, key.Type(), fp)
+	}
+
+	answer, ok := prompt(protocols.Prompt{
+		Title: "Неизвестный SSH-сервер",
+		Message: fmt.Sprintf("Подлинность сервера %s не подтверждена.\nКлюч %s: %s\n\nВведите «да», чтобы доверять этому ключу.",
+			hostname, key.Type(), fp),
+		Echo: true,
+	})
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "да", "yes", "y":
+	default:
+		ok = false
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s presented %s %s and was not trusted", ErrUnknown, hostname, key.Type(), fp)
+	}
+
+	path := AppKnownHostsPath()
+	line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save host key: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("failed to save host key: %w", err)
+	}
+	logger.Info("SSH: trusted host key %s %s for %s, saved to %s", key.Type(), fp, hostname, path)
+	return nil
+}
+
+// probeKey matches no known key, so the lookup reports all keys of a host.
+type probeKey struct{}
+
+func (probeKey) Type() string                                 { return "probe" }
+func (probeKey) Marshal() []byte                              { return []byte("probe") }
+func (probeKey) Verify(data []byte, sig *ssh.Signature) error { return errors.New("probe key") }
@@ -11,9 +11,14 @@ type KillSwitch struct {
 	enabled      bool
 	allowLAN     bool
 	vpnServerIP  string
+	extraServers []string
 	vpnInterface string
 	allowedProcs []string
 	rulesCreated bool
+
+	// TCP MSS clamping on the VPN interface, independent of the kill switch.
+	mssInterface string
+	mssValue     int // clamp value where the firewall cannot derive it from the PMTU
 }
 
 // Config represents kill switch configuration.
@@ -21,10 +26,20 @@ type Config struct {
 	Enabled          bool
 	AllowLAN         bool
 	VPNServerIP      string
+	ExtraServerIPs   []string // further servers, e.g. OpenVPN fallback remotes
 	VPNInterface     string
 	AllowedProcesses []string
 }
 
+// serverIPs returns VPNServerIP and ExtraServerIPs.
+func (c *Config) serverIPs() []string {
+	var ips []string
+	if c.VPNServerIP != "" {
+		ips = append(ips, c.VPNServerIP)
+	}
+	return append(ips, c.ExtraServerIPs...)
+}
+
 // New creates a new kill switch manager.
 func New() *KillSwitch {
 	return &KillSwitch{}
@@ -15,13 +15,18 @@ const pfAnchor = "com.vpnclient.killswitch"
 func (k *KillSwitch) Enable(cfg *Config) error {
 	k.mu.Lock()
 	defer k.mu.Unlock()
+	return k.enableUnsafe(cfg)
+}
 
+// enableUnsafe builds and loads the ruleset; k.mu must be held.
+func (k *KillSwitch) enableUnsafe(cfg *Config) error {
 	if k.rulesCreated {
 		k.disableUnsafe()
 	}
 
 	k.allowLAN = cfg.AllowLAN
 	k.vpnServerIP = cfg.VPNServerIP
+	k.extraServers = cfg.ExtraServerIPs
 	k.vpnInterface = cfg.VPNInterface
 	k.allowedProcs = cfg.AllowedProcesses
 
@@ -29,15 +34,20 @@ func (k *KillSwitch) Enable(cfg *Config) error {
 	var rules strings.Builder
 	rules.WriteString("# VPN Client Kill Switch\n")
 
+	// Normalization rules must precede filter rules
+	if k.mssInterface != "" {
+		rules.WriteString(mssScrubRule(k.mssInterface, k.mssValue))
+	}
+
 	// Allow loopback
 	rules.WriteString("pass quick on lo0 all\n")
 
 	// Allow DHCP
 	rules.WriteString("pass out quick proto udp from any port 68 to any port 67\n")
 
-	// Allow VPN server
-	if cfg.VPNServerIP != "" {
-		rules.WriteString(fmt.Sprintf("pass out quick to %s\n", cfg.VPNServerIP))
+	// Allow VPN servers
+	for _, ip := range cfg.serverIPs() {
+		rules.WriteString(fmt.Sprintf("pass out quick to %s\n", ip))
 	}
 
 	// Allow VPN interface
@@ -86,6 +96,11 @@ func (k *KillSwitch) disableUnsafe() error {
 	// Remove temp file
 	os.Remove("/tmp/vpnclient_pf.conf")
 
+	// The default ruleset evaluates com.apple/* anchors; keep clamping there
+	if k.mssInterface != "" {
+		loadMSSAnchor(k.mssInterface, k.mssValue)
+	}
+
 	k.enabled = false
 	k.rulesCreated = false
 	return nil
@@ -103,15 +118,5 @@ func (k *KillSwitch) UpdateVPNInterface(interfaceName string) error {
 	k.vpnInterface = interfaceName
 
 	// Re-enable with updated config
-	k.disableUnsafe()
-	k.mu.Unlock()
-	err := k.Enable(&Config{
-		Enabled:          true,
-		AllowLAN:         k.allowLAN,
-		VPNServerIP:      k.vpnServerIP,
-		VPNInterface:     interfaceName,
-		AllowedProcesses: k.allowedProcs,
-	})
-	k.mu.Lock()
-	return err
+	return k.reloadUnsafe()
 }
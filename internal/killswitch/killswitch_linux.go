@@ -20,6 +20,7 @@ func (k *KillSwitch) Enable(cfg *Config) error {
 
 	k.allowLAN = cfg.AllowLAN
 	k.vpnServerIP = cfg.VPNServerIP
+	k.extraServers = cfg.ExtraServerIPs
 	k.vpnInterface = cfg.VPNInterface
 	k.allowedProcs = cfg.AllowedProcesses
 
@@ -33,9 +34,9 @@ func (k *KillSwitch) Enable(cfg *Config) error {
 	// Allow DHCP
 	exec.Command("iptables", "-A", chainName, "-p", "udp", "--sport", "68", "--dport", "67", "-j", "ACCEPT").CombinedOutput()
 
-	// Allow VPN server
-	if cfg.VPNServerIP != "" {
-		exec.Command("iptables", "-A", chainName, "-d", cfg.VPNServerIP, "-j", "ACCEPT").CombinedOutput()
+	// Allow VPN servers
+	for _, ip := range cfg.serverIPs() {
+		exec.Command("iptables", "-A", chainName, "-d", ip, "-j", "ACCEPT").CombinedOutput()
 	}
 
 	// Allow VPN interface
@@ -5,6 +5,7 @@ package killswitch
 import (
 	"fmt"
 	"os/exec"
+	"strings"
 
 	"github.com/user/vpn-client/internal/procutil"
 )
@@ -22,6 +23,7 @@ func (k *KillSwitch) Enable(cfg *Config) error {
 
 	k.allowLAN = cfg.AllowLAN
 	k.vpnServerIP = cfg.VPNServerIP
+	k.extraServers = cfg.ExtraServerIPs
 	k.vpnInterface = cfg.VPNInterface
 	k.allowedProcs = cfg.AllowedProcesses
 
@@ -36,8 +38,8 @@ func (k *KillSwitch) Enable(cfg *Config) error {
 		k.disableUnsafe()
 		return fmt.Errorf("failed to allow DHCP: %w", err)
 	}
-	if cfg.VPNServerIP != "" {
-		if err := k.allowVPNServer(cfg.VPNServerIP); err != nil {
+	if ips := cfg.serverIPs(); len(ips) > 0 {
+		if err := k.allowVPNServer(strings.Join(ips, ",")); err != nil {
 			k.disableUnsafe()
 			return fmt.Errorf("failed to allow VPN server: %w", err)
 		}
@@ -0,0 +1,80 @@
+//go:build darwin
+
+package killswitch
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// mssAnchor is evaluated by the scrub-anchor "com.apple/*" of the stock
+// /etc/pf.conf, so clamping works without replacing the main ruleset.
+const mssAnchor = "com.apple/vpnclient.mss"
+
+// EnableMSSClamp limits the MSS of TCP connections over interfaceName to
+// mtu minus 40 bytes of headers, using a pf scrub rule. When the kill
+// switch is on, the rule is part of its ruleset.
+func (k *KillSwitch) EnableMSSClamp(interfaceName string, mtu int) error {
+	if mtu <= 40 {
+		return fmt.Errorf("invalid MTU %d for MSS clamping", mtu)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.mssInterface = interfaceName
+	k.mssValue = mtu - 40
+
+	if k.rulesCreated {
+		return k.reloadUnsafe()
+	}
+	if err := loadMSSAnchor(interfaceName, k.mssValue); err != nil {
+		k.mssInterface = ""
+		return err
+	}
+	return nil
+}
+
+// DisableMSSClamp removes the scrub rule added by EnableMSSClamp.
+func (k *KillSwitch) DisableMSSClamp() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.mssInterface == "" {
+		return
+	}
+	k.mssInterface = ""
+	exec.Command("pfctl", "-a", mssAnchor, "-F", "all").CombinedOutput()
+	if k.rulesCreated {
+		k.reloadUnsafe()
+	}
+}
+
+// reloadUnsafe re-applies the kill switch ruleset with the current
+// settings; k.mu must be held.
+func (k *KillSwitch) reloadUnsafe() error {
+	return k.enableUnsafe(&Config{
+		Enabled:          true,
+		AllowLAN:         k.allowLAN,
+		VPNServerIP:      k.vpnServerIP,
+		ExtraServerIPs:   k.extraServers,
+		VPNInterface:     k.vpnInterface,
+		AllowedProcesses: k.allowedProcs,
+	})
+}
+
+func mssScrubRule(interfaceName string, mss int) string {
+	return fmt.Sprintf("scrub out on %s proto tcp all max-mss %d\n", interfaceName, mss)
+}
+
+func loadMSSAnchor(interfaceName string, mss int) error {
+	cmd := exec.Command("pfctl", "-a", mssAnchor, "-f", "-")
+	cmd.Stdin = strings.NewReader(mssScrubRule(interfaceName, mss))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to enable MSS clamping: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	// pf may be disabled; "already enabled" is not an error here.
+	exec.Command("pfctl", "-e").CombinedOutput()
+	return nil
+}
@@ -0,0 +1,101 @@
+//go:build linux
+
+package killswitch
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	mssChainName = "VPN_MSSCLAMP"
+	mssNftTable  = "vpnclient_mss"
+)
+
+// EnableMSSClamp rewrites the MSS of TCP SYNs leaving interfaceName to fit
+// the path MTU, so that large segments are not black-holed on networks
+// that drop ICMP. iptables TCPMSS is used, or nftables if iptables is not
+// available. mtu is unused: the kernel derives the value from the route.
+func (k *KillSwitch) EnableMSSClamp(interfaceName string, mtu int) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.disableMSSClampUnsafe()
+
+	if _, err := exec.LookPath("iptables"); err == nil {
+		if err := iptablesMSSClamp("iptables", interfaceName); err != nil {
+			iptablesMSSUnclamp("iptables")
+			return err
+		}
+		// IPv6 is best effort; the host may have it disabled.
+		if err := iptablesMSSClamp("ip6tables", interfaceName); err != nil {
+			iptablesMSSUnclamp("ip6tables")
+		}
+	} else if err := nftMSSClamp(interfaceName); err != nil {
+		exec.Command("nft", "delete", "table", "inet", mssNftTable).CombinedOutput()
+		return err
+	}
+
+	k.mssInterface = interfaceName
+	return nil
+}
+
+// DisableMSSClamp removes the rules added by EnableMSSClamp.
+func (k *KillSwitch) DisableMSSClamp() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.disableMSSClampUnsafe()
+}
+
+func (k *KillSwitch) disableMSSClampUnsafe() {
+	if k.mssInterface == "" {
+		return
+	}
+	iptablesMSSUnclamp("iptables")
+	iptablesMSSUnclamp("ip6tables")
+	exec.Command("nft", "delete", "table", "inet", mssNftTable).CombinedOutput()
+	k.mssInterface = ""
+}
+
+func iptablesMSSClamp(bin, interfaceName string) error {
+	// Remove a chain left over from a crash first.
+	iptablesMSSUnclamp(bin)
+
+	steps := [][]string{
+		{"-t", "mangle", "-N", mssChainName},
+		{"-t", "mangle", "-A", mssChainName, "-o", interfaceName, "-p", "tcp",
+			"--tcp-flags", "SYN,RST", "SYN", "-j", "TCPMSS", "--clamp-mss-to-pmtu"},
+		{"-t", "mangle", "-I", "POSTROUTING", "1", "-j", mssChainName},
+	}
+	for _, args := range steps {
+		if out, err := exec.Command(bin, args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to enable MSS clamping (%s): %w: %s", bin, err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+func iptablesMSSUnclamp(bin string) {
+	exec.Command(bin, "-t", "mangle", "-D", "POSTROUTING", "-j", mssChainName).CombinedOutput()
+	exec.Command(bin, "-t", "mangle", "-F", mssChainName).CombinedOutput()
+	exec.Command(bin, "-t", "mangle", "-X", mssChainName).CombinedOutput()
+}
+
+func nftMSSClamp(interfaceName string) error {
+	ruleset := fmt.Sprintf(`table inet %s {
+	chain postrouting {
+		type filter hook postrouting priority mangle; policy accept;
+		oifname %q tcp flags syn tcp option maxseg size set rt mtu
+	}
+}
+`, mssNftTable, interfaceName)
+
+	exec.Command("nft", "delete", "table", "inet", mssNftTable).CombinedOutput()
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(ruleset)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to enable MSS clamping (nft): %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
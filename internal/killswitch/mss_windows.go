@@ -0,0 +1,14 @@
+//go:build windows
+
+package killswitch
+
+import "fmt"
+
+// EnableMSSClamp is not available: Windows Firewall cannot rewrite TCP
+// options. The adapter MTU still bounds the MSS of local connections.
+func (k *KillSwitch) EnableMSSClamp(interfaceName string, mtu int) error {
+	return fmt.Errorf("MSS clamping is not supported on Windows")
+}
+
+// DisableMSSClamp is a no-op on Windows.
+func (k *KillSwitch) DisableMSSClamp() {}
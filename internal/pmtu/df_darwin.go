@@ -0,0 +1,27 @@
+//go:build darwin
+
+package pmtu
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// socketControl sets DF on probes; fwmark is Linux-only and ignored.
+func socketControl(v6 bool, fwmark uint32) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var opErr error
+		err := c.Control(func(fd uintptr) {
+			if v6 {
+				opErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_DONTFRAG, 1)
+			} else {
+				opErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_DONTFRAG, 1)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return opErr
+	}
+}
@@ -0,0 +1,31 @@
+//go:build linux
+
+package pmtu
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// socketControl sets DF and the fwmark on probes. PMTUDISC_PROBE also
+// ignores the kernel's cached path MTU, so every probe size is really sent.
+func socketControl(v6 bool, fwmark uint32) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var opErr error
+		err := c.Control(func(fd uintptr) {
+			if v6 {
+				opErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_MTU_DISCOVER, unix.IPV6_PMTUDISC_PROBE)
+			} else {
+				opErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_PROBE)
+			}
+			if opErr == nil && fwmark != 0 {
+				opErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, int(fwmark))
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return opErr
+	}
+}
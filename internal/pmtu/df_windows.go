@@ -0,0 +1,33 @@
+//go:build windows
+
+package pmtu
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// Winsock option numbers (ws2ipdef.h).
+const (
+	ipDontFragment = 14 // IP_DONTFRAGMENT
+	ipv6DontFrag   = 14 // IPV6_DONTFRAG
+)
+
+// socketControl sets DF on probes; fwmark is Linux-only and ignored.
+func socketControl(v6 bool, fwmark uint32) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var opErr error
+		err := c.Control(func(fd uintptr) {
+			if v6 {
+				opErr = windows.SetsockoptInt(windows.Handle(fd), windows.IPPROTO_IPV6, ipv6DontFrag, 1)
+			} else {
+				opErr = windows.SetsockoptInt(windows.Handle(fd), windows.IPPROTO_IP, ipDontFragment, 1)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return opErr
+	}
+}
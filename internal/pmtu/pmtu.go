@@ -0,0 +1,181 @@
+// Package pmtu discovers the path MTU to a host with ICMP echo probes sent
+// with the Don't Fragment bit set.
+package pmtu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/logger"
+)
+
+const (
+	// MaxMTU is the largest path MTU that is probed.
+	MaxMTU = 1500
+
+	probeTimeout  = 500 * time.Millisecond
+	probeAttempts = 2
+)
+
+// Resolve returns the interface MTU for m. A fixed MTU is returned as is;
+// for config.MTUAuto the path to server is probed and overhead (the
+// tunnel's per-packet encapsulation) is subtracted. If probing fails,
+// config.DefaultMTU is used. fwmark is applied to probes on Linux so they
+// bypass policy routing like the tunnel's own packets.
+func Resolve(m config.MTU, server string, overhead int, fwmark uint32) int {
+	if !m.IsAuto() {
+		return m.Value()
+	}
+
+	addr, err := netip.ParseAddr(server)
+	if err != nil {
+		logger.Warning("MTU auto: invalid server address %q, using %d", server, config.DefaultMTU)
+		return config.DefaultMTU
+	}
+
+	pathMTU, err := Probe(addr, fwmark)
+	if err != nil {
+		logger.Warning("MTU auto: path MTU discovery to %s failed, using %d: %v", addr, config.DefaultMTU, err)
+		return config.DefaultMTU
+	}
+
+	mtu := pathMTU - overhead
+	if min := minMTU(addr); mtu < min {
+		mtu = min
+	}
+	logger.Info("MTU auto: path MTU to %s is %d, tunnel MTU %d", addr, pathMTU, mtu)
+	return mtu
+}
+
+// Probe returns the largest packet size up to MaxMTU that reaches dst
+// unfragmented. It needs permission to open raw ICMP sockets.
+func Probe(dst netip.Addr, fwmark uint32) (int, error) {
+	dst = dst.Unmap()
+	p, err := newProber(dst, fwmark)
+	if err != nil {
+		return 0, err
+	}
+	defer p.conn.Close()
+
+	lo, hi := minMTU(dst), MaxMTU
+	// Most paths carry full-size packets; check that first.
+	if p.fits(hi) {
+		return hi, nil
+	}
+	if !p.fits(lo) {
+		return 0, fmt.Errorf("no echo reply from %s (ICMP blocked?)", dst)
+	}
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if p.fits(mid) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo, nil
+}
+
+// minMTU is the smallest MTU every path must support.
+func minMTU(addr netip.Addr) int {
+	if addr.Is6() && !addr.Is4In6() {
+		return 1280
+	}
+	return 576
+}
+
+type prober struct {
+	conn *net.IPConn
+	dst  *net.IPAddr
+	v6   bool
+	id   int
+	seq  int
+}
+
+func newProber(dst netip.Addr, fwmark uint32) (*prober, error) {
+	v6 := dst.Is6()
+	network, laddr := "ip4:icmp", "0.0.0.0"
+	if v6 {
+		network, laddr = "ip6:ipv6-icmp", "::"
+	}
+
+	lc := net.ListenConfig{Control: socketControl(v6, fwmark)}
+	pc, err := lc.ListenPacket(context.Background(), network, laddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ICMP socket: %w", err)
+	}
+
+	return &prober{
+		conn: pc.(*net.IPConn),
+		dst:  &net.IPAddr{IP: dst.AsSlice()},
+		v6:   v6,
+		id:   os.Getpid() & 0xffff,
+	}, nil
+}
+
+// fits reports whether an IP packet of size bytes gets an echo reply.
+func (p *prober) fits(size int) bool {
+	ipHeader, proto := 20, 1
+	var echo, reply icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if p.v6 {
+		ipHeader, proto = 40, 58
+		echo, reply = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	buf := make([]byte, MaxMTU+100)
+	for attempt := 0; attempt < probeAttempts; attempt++ {
+		p.seq = (p.seq + 1) & 0xffff
+		msg := icmp.Message{
+			Type: echo,
+			Body: &icmp.Echo{ID: p.id, Seq: p.seq, Data: make([]byte, size-ipHeader-8)},
+		}
+		b, err := msg.Marshal(nil)
+		if err != nil {
+			return false
+		}
+		if _, err := p.conn.WriteTo(b, p.dst); err != nil {
+			// EMSGSIZE: larger than the local interface MTU.
+			return false
+		}
+
+		p.conn.SetReadDeadline(time.Now().Add(probeTimeout))
+		for {
+			n, from, err := p.conn.ReadFrom(buf)
+			if err != nil {
+				var ne net.Error
+				if errors.As(err, &ne) && ne.Timeout() {
+					break
+				}
+				return false
+			}
+			m, err := icmp.ParseMessage(proto, buf[:n])
+			if err != nil {
+				continue
+			}
+			switch m.Type {
+			case reply:
+				e, ok := m.Body.(*icmp.Echo)
+				if ok && e.ID == p.id && e.Seq == p.seq && from.String() == p.dst.String() {
+					return true
+				}
+			case ipv4.ICMPTypeDestinationUnreachable:
+				if m.Code == 4 { // fragmentation needed
+					return false
+				}
+			case ipv6.ICMPTypePacketTooBig:
+				return false
+			}
+		}
+	}
+	return false
+}
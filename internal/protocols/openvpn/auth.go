@@ -0,0 +1,212 @@
+package openvpn
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+	"github.com/user/vpn-client/internal/totp"
+)
+
+var errPromptCancelled = errors.New("cancelled by user")
+
+// staticChallenge is the static-challenge of the profile. OpenVPN announces
+// it with the credentials request as "SC:<flags>,<text>".
+type staticChallenge struct {
+	text   string
+	echo   bool
+	concat bool // append the response to the password instead of SCRV1
+}
+
+func parseStaticChallenge(body string) *staticChallenge {
+	_, sc, ok := strings.Cut(body, " SC:")
+	if !ok {
+		return nil
+	}
+	flagStr, text, _ := strings.Cut(sc, ",")
+	flags, _ := strconv.Atoi(flagStr)
+	return &staticChallenge{text: text, echo: flags&1 != 0, concat: flags&2 != 0}
+}
+
+// dynamicChallenge is a CRV1 challenge sent by the server with an auth
+// failure: "CRV1:<flags>:<state_id>:<base64 username>:<text>".
+type dynamicChallenge struct {
+	flags   []string
+	stateID string
+	user    string
+	text    string
+}
+
+func parseDynamicChallenge(body string) *dynamicChallenge {
+	i := strings.Index(body, "CRV1:")
+	if i < 0 {
+		return nil
+	}
+	s := strings.TrimSuffix(body[i+len("CRV1:"):], "']")
+	parts := strings.SplitN(s, ":", 4)
+	if len(parts) < 4 {
+		return nil
+	}
+	user, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil
+	}
+	return &dynamicChallenge{
+		flags:   strings.Split(parts[0], ","),
+		stateID: parts[1],
+		user:    string(user),
+		text:    parts[3],
+	}
+}
+
+func (c *dynamicChallenge) hasFlag(f string) bool {
+	for _, v := range c.flags {
+		if v == f {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPromptFunc sets the function used to ask the user for credentials and
+// challenge responses.
+func (t *Tunnel) SetPromptFunc(fn protocols.PromptFunc) {
+	t.authMu.Lock()
+	t.prompt = fn
+	t.authMu.Unlock()
+}
+
+// ask prompts the user. Answering restarts the connect timeout.
+func (t *Tunnel) ask(message string, echo bool) (string, error) {
+	t.authMu.Lock()
+	prompt := t.prompt
+	t.authMu.Unlock()
+
+	if prompt == nil {
+		return "", fmt.Errorf("no way to ask for input: %s", message)
+	}
+	answer, ok := prompt(protocols.Prompt{Title: "OpenVPN", Message: message, Echo: echo})
+	if !ok {
+		return "", errPromptCancelled
+	}
+
+	select {
+	case t.activity <- struct{}{}:
+	default:
+	}
+	return answer, nil
+}
+
+func (t *Tunnel) setChallenge(c *dynamicChallenge) {
+	t.authMu.Lock()
+	t.challenge = c
+	t.authMu.Unlock()
+}
+
+func (t *Tunnel) takeChallenge() *dynamicChallenge {
+	t.authMu.Lock()
+	defer t.authMu.Unlock()
+	c := t.challenge
+	t.challenge = nil
+	return c
+}
+
+// credentials returns the username and password for a Need 'Auth' request,
+// answering a pending dynamic challenge or the static challenge if any.
+func (t *Tunnel) credentials(body string) (string, string, error) {
+	if c := t.takeChallenge(); c != nil {
+		var response string
+		if c.hasFlag("R") {
+			var err error
+			if response, err = t.ask(c.text, c.hasFlag("E")); err != nil {
+				return "", "", err
+			}
+		}
+		return c.user, "CRV1::" + c.stateID + "::" + response, nil
+	}
+
+	user, pass := t.cfg.AuthUser, t.cfg.AuthPass
+	if user == "" {
+		var err error
+		if user, err = t.ask("Имя пользователя:", true); err != nil {
+			return "", "", err
+		}
+		if pass, err = t.ask("Пароль:", false); err != nil {
+			return "", "", err
+		}
+	}
+
+	if sc := parseStaticChallenge(body); sc != nil {
+		response, err := t.staticResponse(sc)
+		if err != nil {
+			return "", "", err
+		}
+		if sc.concat {
+			pass += response
+		} else {
+			pass = "SCRV1:" + base64.StdEncoding.EncodeToString([]byte(pass)) +
+				":" + base64.StdEncoding.EncodeToString([]byte(response))
+		}
+	}
+	return user, pass, nil
+}
+
+// staticResponse answers a static challenge from the TOTP secret, or asks
+// the user if none is configured.
+func (t *Tunnel) staticResponse(sc *staticChallenge) (string, error) {
+	if t.cfg.TOTPSecret != "" {
+		logger.Info("OpenVPN: answering static challenge with TOTP")
+		return totp.Now(t.cfg.TOTPSecret)
+	}
+	return t.ask(sc.text, sc.echo)
+}
+
+func (t *Tunnel) sendCredentials(m *mgmtClient, body string) {
+	defer logger.Recover("openvpn credentials")
+
+	user, pass, err := t.credentials(body)
+	if err != nil {
+		t.fail("Authentication failed", err)
+		return
+	}
+	if strings.ContainsAny(user+pass, "\r\n") {
+		t.fail("Authentication failed", errors.New("credentials must not contain line breaks"))
+		return
+	}
+
+	for _, cmd := range []string{
+		"username " + quoteArg("Auth") + " " + quoteArg(user),
+		"password " + quoteArg("Auth") + " " + quoteArg(pass),
+	} {
+		if _, err := m.Command(cmd); err != nil {
+			if !errors.Is(err, errMgmtClosed) {
+				t.fail("Failed to send credentials", err)
+			}
+			return
+		}
+	}
+}
+
+// sendPassword answers a password-only request such as 'Private Key'.
+func (t *Tunnel) sendPassword(m *mgmtClient, name string) {
+	defer logger.Recover("openvpn password")
+
+	pass, err := t.ask(fmt.Sprintf("Пароль (%s):", name), false)
+	if err != nil {
+		t.fail("Authentication failed", fmt.Errorf("%s password: %w", name, err))
+		return
+	}
+	if _, err := m.Command("password " + quoteArg(name) + " " + quoteArg(pass)); err != nil && !errors.Is(err, errMgmtClosed) {
+		t.fail("Failed to send password", err)
+	}
+}
+
+// quoteArg quotes s as a management command argument: OpenVPN unescapes
+// backslashes and double quotes inside double-quoted strings.
+func quoteArg(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
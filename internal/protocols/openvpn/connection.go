@@ -41,41 +41,83 @@ func (t *Tunnel) Start(ctx context.Context) error {
 		}
 	}
 
-	// Resolve server IP from config file if possible
-	// For now just use placeholder - will be updated when connected
-	t.ServerIPAddr = ""
-
 	// Config path is required
 	configPath := t.cfg.ConfigPath
 	if configPath == "" {
 		t.SetState(protocols.StateError, "OpenVPN config path required", nil)
 		return fmt.Errorf("openvpn config path is required")
 	}
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+
+	profile, err := ParseProfile(configPath)
+	if err == nil {
+		err = profile.Validate()
+	}
+	if err != nil {
+		t.SetState(protocols.StateError, "Invalid OpenVPN profile", err)
+		return fmt.Errorf("invalid openvpn profile: %w", err)
+	}
+
+	// The server actually used is reported with the CONNECTED state; until
+	// then assume the first remote.
+	servers, err := profile.ResolveServers(t.ctx)
+	if err != nil {
+		t.SetState(protocols.StateError, "Failed to resolve OpenVPN server", err)
+		return err
+	}
+	t.ServerIPAddr = servers[0].String()
+
+	// Private management endpoint with a password file
+	mgmtArgs, err := t.prepareManagement()
+	if err != nil {
+		t.cleanup()
+		t.SetState(protocols.StateError, "Failed to prepare management interface", err)
+		return err
+	}
 
 	// Build command arguments
-	args := []string{
-		"--config", configPath,
-		"--management", "127.0.0.1", strconv.Itoa(managementPort),
+	args := []string{"--config", configPath}
+	args = append(args, mgmtArgs...)
+	args = append(args,
 		"--management-query-passwords",
 		"--management-hold",
+		"--auth-retry", "interact", // keep running after auth failures for CRV1 challenges
 		"--dev", t.ifaceCfg.Name,
 		"--dev-type", "tun",
+		"--suppress-timestamps", // our logger adds its own
+	)
+	args = append(args, pushFilters...)
+	if t.cfg.IgnorePush {
+		args = append(args, ignorePushFilters...)
+	}
+	if profile.Verb < pushLogVerb {
+		// Pushed options are only logged from verb 3 on
+		args = append(args, "--verb", strconv.Itoa(pushLogVerb))
 	}
 
 	// Start OpenVPN process
 	t.process = exec.CommandContext(t.ctx, openVPNPath, args...)
 	procutil.HideWindow(t.process)
-	t.process.Stdout = os.Stdout
-	t.process.Stderr = os.Stderr
+	t.process.Dir = filepath.Dir(configPath) // relative ca/cert/key paths
+	t.captureOutput(t.process)
 
+	t.stopping.Store(false)
 	if err := t.process.Start(); err != nil {
+		t.process = nil
 		t.cleanup()
 		t.SetState(protocols.StateError, "Failed to start OpenVPN", err)
 		return fmt.Errorf("failed to start openvpn: %w", err)
 	}
+	t.exited = make(chan struct{})
+	go t.supervise(t.ctx, t.process, t.exited)
 
 	// Connect to management interface
-	time.Sleep(500 * time.Millisecond) // Give OpenVPN time to start
+	select {
+	case <-time.After(500 * time.Millisecond): // Give OpenVPN time to start
+	case <-t.exited:
+	}
 
 	if err := t.connectManagement(); err != nil {
 		t.cleanup()
@@ -83,32 +125,61 @@ func (t *Tunnel) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to connect to management: %w", err)
 	}
 
-	// Release the hold
-	if err := t.sendCommand("hold release"); err != nil {
-		t.cleanup()
-		t.SetState(protocols.StateError, "Failed to release hold", err)
-		return fmt.Errorf("failed to release hold: %w", err)
+	// Real-time state, log and traffic notifications, then release the hold
+	bytecount := fmt.Sprintf("bytecount %d", int(t.ifaceCfg.StatsPeriod()/time.Second))
+	for _, cmd := range []string{"state on", "log on", bytecount, "hold release"} {
+		if err := t.sendCommand(cmd); err != nil {
+			t.cleanup()
+			t.SetState(protocols.StateError, "Management command failed", err)
+			return fmt.Errorf("management command failed: %w", err)
+		}
+		if cmd == "log on" {
+			t.mgmtLogging.Store(true)
+		}
 	}
+	t.holdAuto.Store(true)
 
-	// Start management monitor
-	go t.monitorManagement()
-
-	// Wait for connection with timeout
-	select {
-	case <-t.connected:
-		t.SetState(protocols.StateConnected, "OpenVPN tunnel established", nil)
-	case <-time.After(60 * time.Second):
-		t.cleanup()
-		t.SetState(protocols.StateError, "Connection timeout", nil)
-		return fmt.Errorf("connection timeout")
-	case <-t.ctx.Done():
+	if err := t.waitConnected(); err != nil {
 		t.cleanup()
-		return t.ctx.Err()
+		if err != t.ctx.Err() {
+			t.SetState(protocols.StateError, "OpenVPN connection failed", err)
+		}
+		return err
 	}
 
+	t.SetState(protocols.StateConnected, "OpenVPN tunnel established", nil)
 	return nil
 }
 
+// waitConnected waits for the CONNECTED state. Answering a prompt restarts
+// the timeout so users have time to type one-time passwords.
+func (t *Tunnel) waitConnected() error {
+	timer := time.NewTimer(connectTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-t.connected:
+			return nil
+		case err := <-t.failed:
+			return err
+		case <-t.exited:
+			return t.exitError()
+		case <-t.mgmt.Done():
+			return fmt.Errorf("management connection lost: %w", t.mgmt.Err())
+		case <-t.activity:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(connectTimeout)
+		case <-timer.C:
+			return fmt.Errorf("connection timeout")
+		case <-t.ctx.Done():
+			return t.ctx.Err()
+		}
+	}
+}
+
 // Stop terminates the OpenVPN tunnel.
 func (t *Tunnel) Stop() error {
 	t.mu.Lock()
@@ -125,7 +196,7 @@ func (t *Tunnel) Stop() error {
 	}
 
 	// Send signal to disconnect
-	if t.mgmtConn != nil {
+	if t.mgmt != nil {
 		t.sendCommand("signal SIGTERM")
 	}
 
@@ -142,7 +213,7 @@ func (t *Tunnel) Reconnect() error {
 	t.SetState(protocols.StateReconnecting, "Reconnecting", nil)
 
 	// Send reconnect command via management
-	if t.mgmtConn != nil {
+	if t.mgmt != nil {
 		if err := t.sendCommand("signal SIGHUP"); err != nil {
 			// Fallback to full restart
 			if err := t.Stop(); err != nil {
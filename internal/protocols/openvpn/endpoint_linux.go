@@ -0,0 +1,12 @@
+//go:build linux
+
+package openvpn
+
+import "path/filepath"
+
+// managementEndpoint places the management interface on a Unix socket in
+// the private instance directory dir.
+func managementEndpoint(dir string) (network, addr string, args []string, err error) {
+	sock := filepath.Join(dir, "management.sock")
+	return "unix", sock, []string{"--management", sock, "unix"}, nil
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package openvpn
+
+import (
+	"net"
+	"strconv"
+)
+
+// managementEndpoint picks a free loopback TCP port for the management
+// interface; dir is unused.
+func managementEndpoint(dir string) (network, addr string, args []string, err error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", "", nil, err
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	addr = net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	return "tcp", addr, []string{"--management", "127.0.0.1", strconv.Itoa(port)}, nil
+}
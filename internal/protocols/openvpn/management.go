@@ -1,72 +1,149 @@
 package openvpn
 
 import (
-	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/user/vpn-client/internal/logger"
 	"github.com/user/vpn-client/internal/protocols"
 )
 
+// prepareManagement creates the private instance directory with a random
+// management password file and returns the --management arguments.
+func (t *Tunnel) prepareManagement() ([]string, error) {
+	dir, err := os.MkdirTemp("", "vpnclient-openvpn-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create instance directory: %w", err)
+	}
+	t.mgmtDir = dir
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate management password: %w", err)
+	}
+	t.mgmtPassword = hex.EncodeToString(secret)
+
+	pwFile := filepath.Join(dir, "management.pw")
+	if err := os.WriteFile(pwFile, []byte(t.mgmtPassword+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write management password: %w", err)
+	}
+
+	network, addr, args, err := managementEndpoint(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pick management endpoint: %w", err)
+	}
+	t.mgmtNetwork, t.mgmtAddr = network, addr
+	return append(args, pwFile), nil
+}
+
 func (t *Tunnel) connectManagement() error {
+	var conn net.Conn
 	var err error
 	for i := 0; i < 10; i++ {
-		t.mgmtConn, err = net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", managementPort), time.Second)
+		conn, err = net.DialTimeout(t.mgmtNetwork, t.mgmtAddr, time.Second)
 		if err == nil {
-			return nil
+			break
 		}
-		time.Sleep(500 * time.Millisecond)
+		select {
+		case <-time.After(500 * time.Millisecond):
+		case <-t.exited:
+			return t.exitError()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to management interface: %w", err)
+	}
+	if t.mgmtNetwork == "unix" {
+		os.Chmod(t.mgmtAddr, 0600)
+	}
+
+	m := newMgmtClient(conn)
+	if err := m.Authenticate(t.mgmtPassword); err != nil {
+		conn.Close()
+		return err
 	}
-	return fmt.Errorf("failed to connect to management interface: %w", err)
+	m.Run(func(line string) { t.handleNotification(m, line) })
+	t.mgmt = m
+	return nil
 }
 
+// sendCommand runs a management command and returns its error, if any.
 func (t *Tunnel) sendCommand(cmd string) error {
-	if t.mgmtConn == nil {
+	if t.mgmt == nil {
 		return fmt.Errorf("management not connected")
 	}
-
-	t.mgmtConn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-	_, err := t.mgmtConn.Write([]byte(cmd + "\n"))
+	_, err := t.mgmt.Command(cmd)
 	return err
 }
 
-func (t *Tunnel) monitorManagement() {
-	scanner := bufio.NewScanner(t.mgmtConn)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Parse management interface messages
-		if strings.HasPrefix(line, ">STATE:") {
-			t.handleStateChange(line)
-		} else if strings.HasPrefix(line, ">BYTECOUNT:") {
-			t.handleByteCount(line)
-		} else if strings.HasPrefix(line, ">PASSWORD:") {
-			t.handlePasswordRequest(line)
-		} else if strings.HasPrefix(line, ">INFO:") {
-			t.handleInfo(line)
-		}
+// fail reports an error that ends the connection attempt. While Start is
+// waiting it picks the error up; once connected, it surfaces as StateError.
+func (t *Tunnel) fail(msg string, err error) {
+	select {
+	case t.failed <- fmt.Errorf("%s: %w", msg, err):
+	default:
+	}
+	if t.State() == protocols.StateConnected {
+		t.SetState(protocols.StateError, msg, err)
+	}
+}
 
-		select {
-		case <-t.ctx.Done():
-			return
-		default:
+// handleNotification dispatches a real-time management message. It runs on
+// the reader goroutine: anything that sends commands runs on its own.
+func (t *Tunnel) handleNotification(m *mgmtClient, line string) {
+	kind, body, _ := strings.Cut(strings.TrimPrefix(line, ">"), ":")
+
+	switch kind {
+	case "STATE":
+		t.handleStateChange(body)
+	case "BYTECOUNT":
+		t.handleByteCount(body)
+	case "LOG":
+		t.handleLog(body)
+	case "INFO":
+		logger.Debug("openvpn: %s", body)
+	case "HOLD":
+		// The hold flag survives restarts, so every SIGHUP/SIGUSR1 ends
+		// in a new hold. Start releases the first one itself.
+		if t.holdAuto.Load() {
+			go t.releaseHold(m)
 		}
+	case "FATAL":
+		logger.Error("openvpn: %s", body)
+		t.output.add("FATAL: " + body)
+		t.fail("OpenVPN fatal error", errors.New(body))
+	case "PASSWORD":
+		t.handlePasswordRequest(m, body)
+	case "NEED-OK":
+		go t.handleNeedOK(m, body)
+	case "NEED-STR":
+		go t.handleNeedStr(m, body)
+	case "CLIENT":
+		t.handleClient(body)
+	default:
+		logger.Debug("openvpn: unhandled management message: %s", line)
 	}
 }
 
-func (t *Tunnel) handleStateChange(line string) {
-	// Format: >STATE:timestamp,state,description,local_ip,remote_ip
-	parts := strings.Split(strings.TrimPrefix(line, ">STATE:"), ",")
+func (t *Tunnel) handleStateChange(body string) {
+	// Format: timestamp,state,description,local_ip,remote_ip,...
+	parts := strings.Split(body, ",")
 	if len(parts) < 2 {
 		return
 	}
 
 	state := parts[1]
+	logger.Debug("openvpn: state %s", strings.Join(parts[1:], ","))
 
 	switch state {
 	case "CONNECTED":
@@ -75,6 +152,16 @@ func (t *Tunnel) handleStateChange(line string) {
 				t.LocalIPAddr = ip
 			}
 		}
+		if len(parts) >= 5 {
+			if ip, err := netip.ParseAddr(parts[4]); err == nil {
+				t.ServerIPAddr = ip.String()
+			}
+		}
+		if len(parts) >= 3 && parts[2] != "SUCCESS" {
+			// CONNECTED,ERROR: up, but some routes or scripts failed
+			logger.Warning("openvpn: connected with errors: %s", parts[2])
+		}
+		t.updateMTU()
 		select {
 		case <-t.connected:
 		default:
@@ -83,37 +170,182 @@ func (t *Tunnel) handleStateChange(line string) {
 		t.SetState(protocols.StateConnected, "Connected", nil)
 
 	case "RECONNECTING":
-		t.SetState(protocols.StateReconnecting, "Reconnecting", nil)
+		reason := "Reconnecting"
+		if len(parts) >= 3 && parts[2] != "" {
+			reason = "Reconnecting: " + parts[2]
+		}
+		t.SetState(protocols.StateReconnecting, reason, nil)
 
 	case "EXITING":
 		t.SetState(protocols.StateDisconnecting, "Exiting", nil)
 
-	case "CONNECTING", "WAIT", "AUTH", "GET_CONFIG":
-		t.SetState(protocols.StateConnecting, state, nil)
+	case "GET_CONFIG":
+		t.resetPushed()
+		if t.State() != protocols.StateReconnecting {
+			t.SetState(protocols.StateConnecting, state, nil)
+		}
+
+	case "CONNECTING", "WAIT", "AUTH", "ASSIGN_IP", "ADD_ROUTES", "RESOLVE", "TCP_CONNECT":
+		if t.State() != protocols.StateReconnecting {
+			t.SetState(protocols.StateConnecting, state, nil)
+		}
 	}
 }
 
-func (t *Tunnel) handleByteCount(line string) {
-	// Format: >BYTECOUNT:bytes_in,bytes_out
-	parts := strings.Split(strings.TrimPrefix(line, ">BYTECOUNT:"), ",")
+func (t *Tunnel) handleByteCount(body string) {
+	// Format: bytes_in,bytes_out
+	parts := strings.Split(body, ",")
 	if len(parts) >= 2 {
 		bytesIn, _ := strconv.ParseUint(parts[0], 10, 64)
 		bytesOut, _ := strconv.ParseUint(parts[1], 10, 64)
-		t.Statistics.BytesReceived = bytesIn
-		t.Statistics.BytesSent = bytesOut
+		t.SetStats(protocols.Stats{BytesReceived: bytesIn, BytesSent: bytesOut})
+	}
+}
+
+// deviceOpenedRe matches the log line naming the TUN device OpenVPN opened:
+// "TUN/TAP device tun0 opened", "Opened utun device utun3" (macOS) or
+// "Wintun device [VPNClient] opened" (Windows).
+var deviceOpenedRe = regexp.MustCompile(`(?:TUN/TAP device (\S+) opened|Opened utun device (\S+)|device \[(.+)\] opened)`)
+
+// updateMTU records the MTU of the TUN device, which OpenVPN sets from the
+// tun-mtu of its configuration.
+func (t *Tunnel) updateMTU() {
+	name := t.device
+	if name == "" {
+		name = t.ifaceCfg.Name
+	}
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		logger.Debug("openvpn: failed to read MTU of %s: %v", name, err)
+		return
 	}
+	t.SetMTU(iface.MTU)
 }
 
-func (t *Tunnel) handlePasswordRequest(line string) {
-	// Format: >PASSWORD:Need 'Auth' username/password
-	if strings.Contains(line, "Auth") && t.cfg.AuthUser != "" {
-		// Send username and password
-		t.sendCommand(fmt.Sprintf("username 'Auth' %s", t.cfg.AuthUser))
-		t.sendCommand(fmt.Sprintf("password 'Auth' %s", t.cfg.AuthPass))
+// handleLog forwards a real-time log line to our logger and picks up
+// pushed options.
+func (t *Tunnel) handleLog(body string) {
+	// Format: timestamp,flags,message
+	parts := strings.SplitN(body, ",", 3)
+	if len(parts) < 3 {
+		return
+	}
+	flags, msg := parts[1], parts[2]
+
+	if strings.HasPrefix(msg, "PUSH: Received control message:") {
+		t.handlePushReply(msg)
+	}
+	if m := deviceOpenedRe.FindStringSubmatch(msg); m != nil {
+		t.device = m[1] + m[2] + m[3]
+	}
+
+	switch {
+	case strings.Contains(flags, "F"), strings.Contains(flags, "N"):
+		logger.Error("openvpn: %s", msg)
+	case strings.Contains(flags, "W"):
+		logger.Warning("openvpn: %s", msg)
+	case strings.Contains(flags, "D"):
+		logger.Debug("openvpn: %s", msg)
+	default:
+		logger.Info("openvpn: %s", msg)
+	}
+}
+
+func (t *Tunnel) releaseHold(m *mgmtClient) {
+	defer logger.Recover("openvpn hold release")
+
+	if _, err := m.Command("hold release"); err != nil && !errors.Is(err, errMgmtClosed) {
+		t.fail("Failed to release hold", err)
+	}
+}
+
+func (t *Tunnel) handlePasswordRequest(m *mgmtClient, body string) {
+	// Formats:
+	//   Need 'Auth' username/password [SC:<flags>,<challenge>]
+	//   Need 'Private Key' password
+	//   Verification Failed: 'Auth' ['CRV1:<flags>:<state>:<user>:<challenge>']
+	//   Auth-Token:<token>
+	switch {
+	case strings.HasPrefix(body, "Verification Failed"):
+		if c := parseDynamicChallenge(body); c != nil {
+			// --auth-retry interact makes OpenVPN ask again; the
+			// response goes with the next credentials request.
+			logger.Info("OpenVPN: server sent a challenge")
+			t.setChallenge(c)
+			return
+		}
+		t.fail("Authentication failed", fmt.Errorf("server rejected credentials for %s", quotedName(body)))
+
+	case strings.HasPrefix(body, "Need 'Auth'"):
+		go t.sendCredentials(m, body)
+
+	case strings.HasPrefix(body, "Need 'Private Key'"):
+		go t.sendPassword(m, "Private Key")
+
+	case strings.HasPrefix(body, "Need "):
+		t.fail("Authentication failed", fmt.Errorf("OpenVPN requested a %s password", quotedName(body)))
+
+	case strings.HasPrefix(body, "Auth-Token:"):
+		logger.Debug("openvpn: received auth token")
 	}
 }
 
-func (t *Tunnel) handleInfo(line string) {
-	// Log info messages
-	// Could be used for additional state tracking
+// handleNeedOK confirms requests such as token insertion; there is nobody
+// to ask, so the message is logged and confirmed.
+func (t *Tunnel) handleNeedOK(m *mgmtClient, body string) {
+	defer logger.Recover("openvpn needok")
+
+	// Format: Need 'name' confirmation MSG:message
+	name := quotedName(body)
+	_, msg, _ := strings.Cut(body, "MSG:")
+	logger.Warning("openvpn: confirming %s: %s", name, msg)
+
+	if _, err := m.Command("needok " + quoteArg(name) + " ok"); err != nil && !errors.Is(err, errMgmtClosed) {
+		t.fail("Failed to confirm "+name, err)
+	}
+}
+
+// handleNeedStr asks the user for the requested string.
+func (t *Tunnel) handleNeedStr(m *mgmtClient, body string) {
+	defer logger.Recover("openvpn needstr")
+
+	// Format: Need 'name' input MSG:message
+	name := quotedName(body)
+	_, msg, _ := strings.Cut(body, "MSG:")
+
+	answer, err := t.ask(msg, true)
+	if err != nil {
+		t.fail("OpenVPN requested input", fmt.Errorf("%s: %w", name, err))
+		return
+	}
+	if _, err := m.Command("needstr " + quoteArg(name) + " " + quoteArg(answer)); err != nil && !errors.Is(err, errMgmtClosed) {
+		t.fail("Failed to answer "+name, err)
+	}
+}
+
+// handleClient logs >CLIENT notifications. They are only sent to servers
+// using --management-client-auth, so there is nothing to answer.
+func (t *Tunnel) handleClient(body string) {
+	// Formats: CONNECT,cid,kid / ESTABLISHED,cid / ... followed by
+	// ENV,name=value lines and a closing ENV,END.
+	if strings.HasPrefix(body, "ENV,") {
+		if body == "ENV,END" {
+			logger.Debug("openvpn: client %s (%d env vars)", t.clientEvent, t.clientEnv)
+			t.clientEvent, t.clientEnv = "", 0
+		} else {
+			t.clientEnv++
+		}
+		return
+	}
+	t.clientEvent = body
+}
+
+// quotedName returns the first 'single-quoted' name in s.
+func quotedName(s string) string {
+	_, rest, ok := strings.Cut(s, "'")
+	if !ok {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, "'")
+	return name
 }
@@ -0,0 +1,226 @@
+package openvpn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mgmtTimeout bounds writes to and replies from the management interface.
+const mgmtTimeout = 10 * time.Second
+
+// errMgmtClosed is returned for commands pending when the connection drops.
+var errMgmtClosed = errors.New("management connection closed")
+
+// mgmtReply is the outcome of a single management command.
+type mgmtReply struct {
+	msg   string   // text after SUCCESS:, or error text after ERROR:
+	lines []string // body of multi-line replies terminated by END
+	err   error
+}
+
+// mgmtWaiter is a command awaiting its reply. OpenVPN answers commands
+// strictly in order, so waiters form a FIFO queue.
+type mgmtWaiter struct {
+	cmd       string
+	multiline bool
+	lines     []string
+	reply     chan mgmtReply
+}
+
+// mgmtClient speaks the OpenVPN management protocol.
+type mgmtClient struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	notify func(line string)
+
+	writeMu sync.Mutex // serializes write+enqueue so replies stay in order
+	mu      sync.Mutex
+	pending []*mgmtWaiter
+	closed  bool
+	done    chan struct{}
+	err     error
+}
+
+func newMgmtClient(conn net.Conn) *mgmtClient {
+	return &mgmtClient{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		done: make(chan struct{}),
+	}
+}
+
+// Authenticate answers the "ENTER PASSWORD:" prompt OpenVPN sends when the
+// management interface has a password file. It must precede Run.
+func (m *mgmtClient) Authenticate(password string) error {
+	m.conn.SetDeadline(time.Now().Add(mgmtTimeout))
+	defer m.conn.SetDeadline(time.Time{})
+
+	prompt, err := m.r.ReadString(':')
+	if err != nil {
+		return fmt.Errorf("failed to read password prompt: %w", err)
+	}
+	if !strings.HasSuffix(prompt, "ENTER PASSWORD:") {
+		return fmt.Errorf("unexpected management greeting %q", prompt)
+	}
+	if _, err := m.conn.Write([]byte(password + "\n")); err != nil {
+		return fmt.Errorf("failed to send password: %w", err)
+	}
+
+	line, err := m.r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read password reply: %w", err)
+	}
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "SUCCESS:") {
+		return fmt.Errorf("management password rejected: %s", line)
+	}
+	return nil
+}
+
+// Run starts reading from the connection. Real-time notifications (lines
+// starting with '>') are passed to notify on the reader goroutine, so notify
+// must not wait for command replies itself.
+func (m *mgmtClient) Run(notify func(line string)) {
+	m.notify = notify
+	go m.readLoop()
+}
+
+// Command sends cmd and waits for its SUCCESS/ERROR reply.
+func (m *mgmtClient) Command(cmd string) (string, error) {
+	r := m.roundTrip(cmd, false)
+	return r.msg, r.err
+}
+
+// CommandLines sends cmd and returns the body of its END-terminated reply.
+func (m *mgmtClient) CommandLines(cmd string) ([]string, error) {
+	r := m.roundTrip(cmd, true)
+	return r.lines, r.err
+}
+
+// Done is closed when the management connection ends.
+func (m *mgmtClient) Done() <-chan struct{} {
+	return m.done
+}
+
+// Err returns why the connection ended, or nil while it is open.
+func (m *mgmtClient) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Close closes the connection and fails all pending commands.
+func (m *mgmtClient) Close() error {
+	err := m.conn.Close()
+	<-m.done
+	return err
+}
+
+func (m *mgmtClient) roundTrip(cmd string, multiline bool) mgmtReply {
+	w := &mgmtWaiter{cmd: cmd, multiline: multiline, reply: make(chan mgmtReply, 1)}
+
+	m.writeMu.Lock()
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		m.writeMu.Unlock()
+		return mgmtReply{err: errMgmtClosed}
+	}
+	m.pending = append(m.pending, w)
+	m.mu.Unlock()
+
+	m.conn.SetWriteDeadline(time.Now().Add(mgmtTimeout))
+	_, err := m.conn.Write([]byte(cmd + "\n"))
+	m.writeMu.Unlock()
+	if err != nil {
+		// The stream is unusable once a write fails; the reader fails the
+		// remaining waiters when the connection closes.
+		m.conn.Close()
+		return mgmtReply{err: fmt.Errorf("failed to send %q: %w", commandName(cmd), err)}
+	}
+
+	select {
+	case r := <-w.reply:
+		return r
+	case <-time.After(mgmtTimeout):
+		return mgmtReply{err: fmt.Errorf("no reply to %q", commandName(cmd))}
+	}
+}
+
+func (m *mgmtClient) readLoop() {
+	defer close(m.done)
+
+	scanner := bufio.NewScanner(m.r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.HasPrefix(line, ">") {
+			m.notify(line)
+			continue
+		}
+		m.deliver(line)
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = errMgmtClosed
+	}
+
+	m.mu.Lock()
+	m.closed = true
+	m.err = err
+	pending := m.pending
+	m.pending = nil
+	m.mu.Unlock()
+
+	for _, w := range pending {
+		w.reply <- mgmtReply{err: fmt.Errorf("%s: %w", commandName(w.cmd), errMgmtClosed)}
+	}
+}
+
+// deliver routes a reply line to the oldest pending command.
+func (m *mgmtClient) deliver(line string) {
+	m.mu.Lock()
+	if len(m.pending) == 0 {
+		m.mu.Unlock()
+		return // unsolicited output, e.g. the password prompt
+	}
+	w := m.pending[0]
+
+	var r *mgmtReply
+	switch {
+	case strings.HasPrefix(line, "SUCCESS:"):
+		r = &mgmtReply{msg: strings.TrimSpace(strings.TrimPrefix(line, "SUCCESS:"))}
+	case strings.HasPrefix(line, "ERROR:"):
+		msg := strings.TrimSpace(strings.TrimPrefix(line, "ERROR:"))
+		r = &mgmtReply{msg: msg, err: fmt.Errorf("%s: %s", commandName(w.cmd), msg)}
+	case w.multiline && line == "END":
+		r = &mgmtReply{lines: w.lines}
+	case w.multiline:
+		w.lines = append(w.lines, line)
+	}
+
+	if r != nil {
+		m.pending = m.pending[1:]
+	}
+	m.mu.Unlock()
+
+	if r != nil {
+		w.reply <- *r
+	}
+}
+
+// commandName returns the verb of cmd, keeping credentials out of errors.
+func commandName(cmd string) string {
+	if i := strings.IndexByte(cmd, ' '); i >= 0 {
+		return cmd[:i]
+	}
+	return cmd
+}
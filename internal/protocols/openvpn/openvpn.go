@@ -3,4 +3,5 @@
 // - tunnel.go: Tunnel struct, New, helpers
 // - connection.go: Start, Stop, Reconnect
 // - management.go: Management interface handling
+// - mgmtclient.go: Management protocol client
 package openvpn
@@ -0,0 +1,483 @@
+package openvpn
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/vpn-client/internal/logger"
+)
+
+const (
+	defaultPort  = 1194
+	defaultProto = "udp"
+
+	resolveTimeout = 10 * time.Second
+)
+
+// Remote is a server from a remote line of the profile.
+type Remote struct {
+	Host  string
+	Port  int
+	Proto string // udp, tcp-client, udp6, ...
+}
+
+// Network returns the address family for resolving the remote: "ip4",
+// "ip6" or "ip" for either.
+func (r Remote) Network() string {
+	switch {
+	case strings.HasSuffix(r.Proto, "4") || strings.HasSuffix(r.Proto, "4-client"):
+		return "ip4"
+	case strings.HasSuffix(r.Proto, "6") || strings.HasSuffix(r.Proto, "6-client"):
+		return "ip6"
+	}
+	return "ip"
+}
+
+// Profile is what the client needs from an .ovpn file.
+type Profile struct {
+	Path    string
+	Remotes []Remote
+
+	// Key material: file paths (relative to the profile directory) or
+	// PEM text of inline <ca>, <cert> and <key> blocks.
+	CA, Cert, Key       string
+	CAInline            bool
+	CertInline          bool
+	KeyInline           bool
+	HasExternalIdentity bool // pkcs12, cryptoapicert or management-external-key
+
+	// Alternatives to ca for verifying the server
+	CAPath          string // directory of hashed CA certificates and CRLs
+	PeerFingerprint bool   // peer-fingerprint pins the server certificate
+
+	Verb int // log verbosity, OpenVPN's default is 1
+}
+
+// ParseProfile reads an .ovpn file. Top-level options are defaults for
+// <connection> blocks; when there are blocks, their remotes are used.
+func ParseProfile(path string) (*Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open profile: %w", err)
+	}
+	defer f.Close()
+
+	p := &Profile{Path: path, Verb: 1}
+	port, proto := defaultPort, defaultProto
+
+	var topRemotes []Remote
+	var blocks [][]Remote
+	var inConnection bool
+	var connPort int
+	var connProtoName string
+	var connRemotes []Remote
+
+	// Inline blocks: <tag> ... </tag>
+	var inlineTag string
+	var inline strings.Builder
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+
+		if inlineTag != "" {
+			if line == "</"+inlineTag+">" {
+				p.setInline(inlineTag, inline.String())
+				inlineTag = ""
+				inline.Reset()
+			} else {
+				inline.WriteString(line)
+				inline.WriteByte('\n')
+			}
+			continue
+		}
+
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		switch {
+		case line == "<connection>":
+			inConnection = true
+			connPort, connProtoName, connRemotes = 0, "", nil
+			continue
+		case line == "</connection>":
+			for i := range connRemotes {
+				if connRemotes[i].Port == 0 {
+					connRemotes[i].Port = connPort
+				}
+				if connRemotes[i].Proto == "" {
+					connRemotes[i].Proto = connProtoName
+				}
+			}
+			blocks = append(blocks, connRemotes)
+			inConnection = false
+			continue
+		case strings.HasPrefix(line, "<") && strings.HasSuffix(line, ">") && !strings.HasPrefix(line, "</"):
+			inlineTag = line[1 : len(line)-1]
+			continue
+		}
+
+		fields, err := splitFields(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filepath.Base(path), lineNo, err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(fields[0], "--")
+		args := fields[1:]
+
+		switch name {
+		case "remote":
+			if len(args) < 1 {
+				return nil, fmt.Errorf("%s:%d: remote needs a host", filepath.Base(path), lineNo)
+			}
+			r := Remote{Host: args[0]}
+			if len(args) > 1 {
+				if r.Port, err = parsePort(args[1]); err != nil {
+					return nil, fmt.Errorf("%s:%d: %w", filepath.Base(path), lineNo, err)
+				}
+			}
+			if len(args) > 2 {
+				r.Proto = args[2]
+			}
+			if inConnection {
+				connRemotes = append(connRemotes, r)
+			} else {
+				topRemotes = append(topRemotes, r)
+			}
+
+		case "port", "rport":
+			if len(args) < 1 {
+				continue
+			}
+			n, err := parsePort(args[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", filepath.Base(path), lineNo, err)
+			}
+			if inConnection {
+				connPort = n
+			} else {
+				port = n
+			}
+
+		case "proto":
+			if len(args) < 1 {
+				continue
+			}
+			if inConnection {
+				connProtoName = args[0]
+			} else {
+				proto = args[0]
+			}
+
+		case "ca", "cert", "key":
+			if len(args) < 1 || args[0] == "[inline]" {
+				continue
+			}
+			p.setFile(name, args[0])
+
+		case "capath":
+			if len(args) >= 1 {
+				p.CAPath = args[0]
+			}
+
+		case "peer-fingerprint":
+			p.PeerFingerprint = true
+
+		case "verb":
+			if len(args) >= 1 {
+				if n, err := strconv.Atoi(args[0]); err == nil {
+					p.Verb = n
+				}
+			}
+
+		case "pkcs12", "cryptoapicert", "management-external-key", "management-external-cert":
+			p.HasExternalIdentity = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+	if inlineTag != "" {
+		return nil, fmt.Errorf("%s: unterminated <%s> block", filepath.Base(path), inlineTag)
+	}
+
+	remotes := topRemotes
+	if len(blocks) > 0 {
+		remotes = nil
+		for _, b := range blocks {
+			remotes = append(remotes, b...)
+		}
+	}
+	for _, r := range remotes {
+		if r.Port == 0 {
+			r.Port = port
+		}
+		if r.Proto == "" {
+			r.Proto = proto
+		}
+		p.Remotes = append(p.Remotes, r)
+	}
+	return p, nil
+}
+
+func (p *Profile) setInline(tag, text string) {
+	switch tag {
+	case "ca":
+		p.CA, p.CAInline = text, true
+	case "cert":
+		p.Cert, p.CertInline = text, true
+	case "key":
+		p.Key, p.KeyInline = text, true
+	case "pkcs12":
+		p.HasExternalIdentity = true
+	case "peer-fingerprint":
+		p.PeerFingerprint = true
+	}
+}
+
+func (p *Profile) setFile(name, path string) {
+	switch name {
+	case "ca":
+		p.CA, p.CAInline = path, false
+	case "cert":
+		p.Cert, p.CertInline = path, false
+	case "key":
+		p.Key, p.KeyInline = path, false
+	}
+}
+
+// Validate checks that the profile has a remote and a way to verify the
+// server (ca, capath or peer-fingerprint), and that its CA, certificate
+// and key exist, parse, and belong together.
+func (p *Profile) Validate() error {
+	if len(p.Remotes) == 0 {
+		return fmt.Errorf("profile has no remote")
+	}
+
+	if p.HasExternalIdentity {
+		return nil
+	}
+	switch {
+	case p.CA != "":
+		caPEM, err := p.material("ca", p.CA, p.CAInline)
+		if err != nil {
+			return err
+		}
+		if _, err := parseCertificates("ca", caPEM); err != nil {
+			return err
+		}
+	case p.CAPath != "":
+		if info, err := os.Stat(p.resolve(p.CAPath)); err != nil {
+			return fmt.Errorf("capath: %w", err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("capath: %s is not a directory", p.CAPath)
+		}
+	case !p.PeerFingerprint:
+		return fmt.Errorf("profile has no ca, capath or peer-fingerprint")
+	}
+
+	// Username/password-only profiles have no client certificate
+	if p.Cert == "" && p.Key == "" {
+		return nil
+	}
+	if p.Cert == "" || p.Key == "" {
+		return fmt.Errorf("profile needs both cert and key")
+	}
+	certPEM, err := p.material("cert", p.Cert, p.CertInline)
+	if err != nil {
+		return err
+	}
+	certs, err := parseCertificates("cert", certPEM)
+	if err != nil {
+		return err
+	}
+	keyPEM, err := p.material("key", p.Key, p.KeyInline)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil || !strings.Contains(block.Type, "PRIVATE KEY") {
+		return fmt.Errorf("key: no PEM private key found")
+	}
+
+	if now := time.Now(); now.After(certs[0].NotAfter) {
+		logger.Warning("OpenVPN: client certificate expired on %s", certs[0].NotAfter.Format("2006-01-02"))
+	}
+
+	// Encrypted keys are checked by OpenVPN once the passphrase is known
+	if block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] != "" {
+		return nil
+	}
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return fmt.Errorf("cert and key do not match: %w", err)
+	}
+	return nil
+}
+
+// material returns inline PEM text or reads a file relative to the profile.
+func (p *Profile) material(name, value string, inline bool) ([]byte, error) {
+	if inline {
+		return []byte(value), nil
+	}
+	data, err := os.ReadFile(p.resolve(value))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return data, nil
+}
+
+// resolve returns path relative to the profile directory.
+func (p *Profile) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(p.Path), path)
+}
+
+func parseCertificates(name string, data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s: no PEM certificate found", name)
+	}
+	return certs, nil
+}
+
+// ResolveServers resolves all remotes and returns their unique addresses.
+// Unresolvable remotes are logged and skipped.
+func (p *Profile) ResolveServers(ctx context.Context) ([]netip.Addr, error) {
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	seen := make(map[netip.Addr]bool)
+	var addrs []netip.Addr
+	var lastErr error
+	for _, r := range p.Remotes {
+		ips, err := net.DefaultResolver.LookupNetIP(ctx, r.Network(), r.Host)
+		if err != nil {
+			logger.Warning("OpenVPN: failed to resolve remote %s: %v", r.Host, err)
+			lastErr = err
+			continue
+		}
+		for _, ip := range ips {
+			ip = ip.Unmap()
+			if !seen[ip] {
+				seen[ip] = true
+				addrs = append(addrs, ip)
+			}
+		}
+	}
+	if len(addrs) == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("profile has no remote")
+		}
+		return nil, fmt.Errorf("failed to resolve servers: %w", lastErr)
+	}
+	return addrs, nil
+}
+
+// ServerIPs parses the profile at path and resolves its servers.
+func ServerIPs(ctx context.Context, path string) ([]string, error) {
+	p, err := ParseProfile(path)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := p.ResolveServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]string, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.String()
+	}
+	return ips, nil
+}
+
+func parsePort(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return n, nil
+}
+
+// splitFields splits a config line into arguments like OpenVPN does:
+// whitespace separated, with "double" or 'single' quoting and backslash
+// escapes outside single quotes. A # or ; starting a field is a comment.
+func splitFields(line string) ([]string, error) {
+	var fields []string
+	var cur strings.Builder
+	inField := false
+	var quote byte
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' && i+1 < len(line) {
+				i++
+				cur.WriteByte(line[i])
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inField = true
+		case c == ' ' || c == '\t':
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		case (c == '#' || c == ';') && !inField:
+			i = len(line)
+		case c == '\\' && i+1 < len(line):
+			i++
+			cur.WriteByte(line[i])
+			inField = true
+		default:
+			cur.WriteByte(c)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inField {
+		fields = append(fields, cur.String())
+	}
+	return fields, nil
+}
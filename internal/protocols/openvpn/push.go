@@ -0,0 +1,151 @@
+package openvpn
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+)
+
+// pushLogVerb is the lowest verbosity that logs PUSH_REPLY messages.
+const pushLogVerb = 3
+
+// pushFilters keep OpenVPN from applying pushed settings itself; routing
+// and DNS are handled by the client.
+var pushFilters = []string{
+	"--route-noexec",
+	"--pull-filter", "ignore", "dhcp-option",
+}
+
+// ignorePushFilters drop pushed routes entirely when the local
+// configuration should win.
+var ignorePushFilters = []string{
+	"--pull-filter", "ignore", "redirect-gateway",
+	"--pull-filter", "ignore", "route ",
+	"--pull-filter", "ignore", "route-ipv6 ",
+}
+
+// Pushed returns the routes and DNS settings pushed by the server, or
+// nothing with ignore_push.
+func (t *Tunnel) Pushed() protocols.PushedConfig {
+	if t.cfg.IgnorePush {
+		return protocols.PushedConfig{}
+	}
+	t.pushMu.Lock()
+	defer t.pushMu.Unlock()
+	return t.pushed
+}
+
+func (t *Tunnel) resetPushed() {
+	t.pushMu.Lock()
+	t.pushed = protocols.PushedConfig{}
+	t.pushMu.Unlock()
+}
+
+// handlePushReply records the options of a PUSH_REPLY seen in the log:
+//
+//	PUSH: Received control message: 'PUSH_REPLY,route 10.0.0.0 255.0.0.0,...'
+//
+// Large pushes arrive in several messages, so options accumulate until the
+// next GET_CONFIG state.
+func (t *Tunnel) handlePushReply(msg string) {
+	_, reply, ok := strings.Cut(msg, "PUSH_REPLY,")
+	if !ok {
+		return
+	}
+	reply = strings.TrimSuffix(reply, "'")
+
+	t.pushMu.Lock()
+	defer t.pushMu.Unlock()
+
+	for _, opt := range strings.Split(reply, ",") {
+		applyPushedOption(&t.pushed, strings.Fields(opt))
+	}
+}
+
+func applyPushedOption(p *protocols.PushedConfig, f []string) {
+	if len(f) == 0 {
+		return
+	}
+
+	switch f[0] {
+	case "route":
+		// route network [netmask] [gateway] [metric]
+		if len(f) < 2 {
+			return
+		}
+		if len(f) >= 4 && f[3] == "net_gateway" {
+			logger.Debug("OpenVPN: ignoring pushed bypass route %s", f[1])
+			return
+		}
+		mask := "255.255.255.255"
+		if len(f) >= 3 {
+			mask = f[2]
+		}
+		if prefix, ok := ipv4Prefix(f[1], mask); ok {
+			p.Routes = append(p.Routes, prefix)
+		} else {
+			logger.Warning("OpenVPN: ignoring pushed route %s %s", f[1], mask)
+		}
+
+	case "route-ipv6":
+		// route-ipv6 prefix [gateway] [metric]
+		if len(f) < 2 {
+			return
+		}
+		if prefix, err := netip.ParsePrefix(f[1]); err == nil {
+			p.Routes = append(p.Routes, prefix.Masked())
+		} else {
+			logger.Warning("OpenVPN: ignoring pushed route %s", f[1])
+		}
+
+	case "redirect-gateway":
+		p.DefaultRoute = true
+		for _, flag := range f[1:] {
+			switch flag {
+			case "ipv6":
+				p.DefaultRoute6 = true
+			case "!ipv4":
+				p.DefaultRoute = false
+			}
+		}
+
+	case "dhcp-option":
+		// dhcp-option DNS|DNS6|DOMAIN|DOMAIN-SEARCH|ADAPTER_DOMAIN_SUFFIX value
+		if len(f) < 3 {
+			return
+		}
+		switch f[1] {
+		case "DNS", "DNS6":
+			if addr, err := netip.ParseAddr(f[2]); err == nil {
+				p.DNSServers = append(p.DNSServers, addr)
+			}
+		case "DOMAIN", "DOMAIN-SEARCH", "ADAPTER_DOMAIN_SUFFIX":
+			p.DNSDomains = append(p.DNSDomains, f[2])
+		}
+	}
+}
+
+// ipv4Prefix converts an address and dotted netmask to a prefix.
+func ipv4Prefix(addr, mask string) (netip.Prefix, bool) {
+	a, err := netip.ParseAddr(addr)
+	if err != nil || !a.Is4() {
+		return netip.Prefix{}, false
+	}
+	m, err := netip.ParseAddr(mask)
+	if err != nil || !m.Is4() {
+		return netip.Prefix{}, false
+	}
+	b := m.As4()
+	v := uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+	bits := 0
+	for v&0x80000000 != 0 {
+		bits++
+		v <<= 1
+	}
+	if v != 0 {
+		return netip.Prefix{}, false // non-contiguous mask
+	}
+	return netip.PrefixFrom(a, bits).Masked(), true
+}
@@ -0,0 +1,148 @@
+package openvpn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+)
+
+// outputLines is how many recent lines of OpenVPN output are kept.
+const outputLines = 200
+
+// logRing keeps the most recent lines of OpenVPN output for diagnostics.
+type logRing struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+}
+
+func (r *logRing) add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.lines) < outputLines {
+		r.lines = append(r.lines, line)
+		return
+	}
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % outputLines
+}
+
+// Lines returns the kept lines, oldest first.
+func (r *logRing) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]string, 0, len(r.lines))
+	out = append(out, r.lines[r.next:]...)
+	return append(out, r.lines[:r.next]...)
+}
+
+func (r *logRing) reset() {
+	r.mu.Lock()
+	r.lines, r.next = nil, 0
+	r.mu.Unlock()
+}
+
+// lastError returns the most recent line reporting an error, or the last
+// line if none does.
+func (r *logRing) lastError() string {
+	lines := r.Lines()
+	for i := len(lines) - 1; i >= 0; i-- {
+		l := strings.ToLower(lines[i])
+		if strings.Contains(l, "error") || strings.Contains(l, "fatal") || strings.Contains(l, "auth_failed") {
+			return lines[i]
+		}
+	}
+	if len(lines) > 0 {
+		return lines[len(lines)-1]
+	}
+	return "no output"
+}
+
+// lineWriter splits process output into lines.
+type lineWriter struct {
+	buf  []byte
+	line func(string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.line(strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) > 64*1024 {
+		w.line(string(w.buf))
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// RecentOutput returns the last lines OpenVPN printed.
+func (t *Tunnel) RecentOutput() []string {
+	return t.output.Lines()
+}
+
+// captureOutput routes the process output into the ring buffer and, until
+// the management log stream takes over, into our logger.
+func (t *Tunnel) captureOutput(cmd *exec.Cmd) {
+	t.output.reset()
+	cmd.Stdout = &lineWriter{line: func(line string) { t.handleOutput(line, false) }}
+	cmd.Stderr = &lineWriter{line: func(line string) { t.handleOutput(line, true) }}
+}
+
+func (t *Tunnel) handleOutput(line string, stderr bool) {
+	if line == "" {
+		return
+	}
+	t.output.add(line)
+
+	if t.mgmtLogging.Load() {
+		return // logged from >LOG with its severity
+	}
+	if stderr {
+		logger.Warning("openvpn: %s", line)
+	} else {
+		logger.Info("openvpn: %s", line)
+	}
+}
+
+// exitError describes why the process exited early.
+func (t *Tunnel) exitError() error {
+	return fmt.Errorf("openvpn exited: %s", t.output.lastError())
+}
+
+// supervise waits for the process and handles an unexpected exit: the
+// attempt fails or, once connected, the tunnel errors and reconnects.
+func (t *Tunnel) supervise(ctx context.Context, cmd *exec.Cmd, exited chan struct{}) {
+	defer logger.Recover("openvpn supervise")
+
+	err := cmd.Wait()
+	close(exited)
+
+	if ctx.Err() != nil || t.stopping.Load() {
+		return
+	}
+
+	reason := t.output.lastError()
+	logger.Error("OpenVPN exited unexpectedly (%v): %s", err, reason)
+
+	wasConnected := t.State() == protocols.StateConnected
+	t.fail("OpenVPN exited", errors.New(reason))
+
+	if wasConnected {
+		go t.Reconnect()
+	}
+}
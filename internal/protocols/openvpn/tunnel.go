@@ -3,18 +3,19 @@ package openvpn
 
 import (
 	"context"
-	"net"
 	"os"
 	"os/exec"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/user/vpn-client/internal/config"
 	"github.com/user/vpn-client/internal/protocols"
 )
 
 const (
-	// Default management interface port
-	managementPort = 17166
+	// connectTimeout bounds the wait for the CONNECTED state
+	connectTimeout = 60 * time.Second
 )
 
 // Tunnel implements the OpenVPN VPN tunnel.
@@ -25,11 +26,32 @@ type Tunnel struct {
 	cfg          *config.OpenVPN
 	ifaceCfg     *config.Interface
 	process      *exec.Cmd
-	mgmtConn     net.Conn
+	mgmt         *mgmtClient
+	mgmtDir      string // private per-instance directory, removed on cleanup
+	mgmtNetwork  string
+	mgmtAddr     string
+	mgmtPassword string
 	ctx          context.Context
 	cancel       context.CancelFunc
 	tempConfPath string
 	connected    chan struct{}
+	failed       chan error    // errors that end a connection attempt
+	holdAuto     atomic.Bool   // release holds after restarts
+	clientEvent  string        // pending >CLIENT header
+	clientEnv    int           // >CLIENT:ENV lines seen
+	activity     chan struct{} // user answered a prompt
+	exited       chan struct{} // closed by supervise when the process exits
+	stopping     atomic.Bool   // process is being stopped on purpose
+	mgmtLogging  atomic.Bool   // log lines arrive via >LOG
+	output       logRing       // recent process output
+	device       string        // TUN device OpenVPN opened; reader goroutine only
+
+	authMu    sync.Mutex
+	prompt    protocols.PromptFunc
+	challenge *dynamicChallenge // pending CRV1 challenge
+
+	pushMu sync.Mutex
+	pushed protocols.PushedConfig
 }
 
 // New creates a new OpenVPN tunnel.
@@ -39,6 +61,8 @@ func New(cfg *config.OpenVPN, ifaceCfg *config.Interface) *Tunnel {
 		cfg:        cfg,
 		ifaceCfg:   ifaceCfg,
 		connected:  make(chan struct{}),
+		failed:     make(chan error, 1),
+		activity:   make(chan struct{}, 1),
 	}
 }
 
@@ -50,17 +74,25 @@ func (t *Tunnel) GetProcess() *exec.Cmd {
 }
 
 func (t *Tunnel) cleanup() {
-	if t.mgmtConn != nil {
-		t.mgmtConn.Close()
-		t.mgmtConn = nil
+	t.holdAuto.Store(false)
+	t.mgmtLogging.Store(false)
+	if t.mgmt != nil {
+		t.mgmt.Close()
+		t.mgmt = nil
 	}
 
 	if t.process != nil && t.process.Process != nil {
+		t.stopping.Store(true)
 		t.process.Process.Kill()
-		t.process.Wait()
+		<-t.exited // supervise owns Wait
 		t.process = nil
 	}
 
+	if t.mgmtDir != "" {
+		os.RemoveAll(t.mgmtDir)
+		t.mgmtDir = ""
+	}
+
 	if t.tempConfPath != "" {
 		os.Remove(t.tempConfPath)
 		t.tempConfPath = ""
@@ -72,4 +104,10 @@ func (t *Tunnel) cleanup() {
 	default:
 	}
 	t.connected = make(chan struct{})
+	select {
+	case <-t.failed:
+	default:
+	}
+	t.setChallenge(nil)
+	t.resetPushed()
 }
@@ -0,0 +1,19 @@
+package protocols
+
+// Prompt is a question asked of the user while a tunnel connects, e.g. for
+// a one-time password or a challenge response.
+type Prompt struct {
+	Title   string
+	Message string
+	Echo    bool // the answer is not secret and may be shown while typed
+}
+
+// PromptFunc asks the user and returns the answer. ok is false if the user
+// cancelled or nobody is there to answer.
+type PromptFunc func(p Prompt) (answer string, ok bool)
+
+// Prompter is implemented by tunnels that may need user input to connect.
+type Prompter interface {
+	// SetPromptFunc sets the function used to ask the user.
+	SetPromptFunc(fn PromptFunc)
+}
@@ -81,6 +81,32 @@ type Tunnel interface {
 
 	// Reconnect attempts to reconnect the tunnel.
 	Reconnect() error
+
+	// MTU returns the interface MTU in use, or 0 if unknown.
+	MTU() int
+}
+
+// MTUProber is implemented by tunnels that can re-run path MTU discovery
+// for mtu: auto while connected, e.g. after a network change.
+type MTUProber interface {
+	// ReprobeMTU probes the path again and applies the result.
+	ReprobeMTU() (int, error)
+}
+
+// PortForward describes a port forward a tunnel keeps over its connection.
+type PortForward struct {
+	Kind    string // "local" (like ssh -L) or "remote" (like ssh -R)
+	Listen  string
+	Connect string
+	Active  bool   // listening
+	Conns   int    // connections being forwarded
+	Error   string // why it is not listening, if not active
+}
+
+// PortForwarder is implemented by tunnels that keep port forwards.
+type PortForwarder interface {
+	// PortForwards returns the state of each configured forward.
+	PortForwards() []PortForward
 }
 
 // BaseTunnel provides common functionality for tunnel implementations.
@@ -93,7 +119,8 @@ type BaseTunnel struct {
 	LocalIPAddr   netip.Addr
 	GatewayIPAddr netip.Addr
 	ServerIPAddr  string
-	Statistics    Stats
+	stats         Stats
+	mtu           int
 }
 
 // NewBaseTunnel creates a new base tunnel.
@@ -136,7 +163,16 @@ func (b *BaseTunnel) StateChanges() <-chan StateChange {
 
 // Stats returns current statistics.
 func (b *BaseTunnel) Stats() Stats {
-	return b.Statistics
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+	return b.stats
+}
+
+// SetStats records the statistics reported by the tunnel.
+func (b *BaseTunnel) SetStats(stats Stats) {
+	b.stateMu.Lock()
+	b.stats = stats
+	b.stateMu.Unlock()
 }
 
 // LocalIP returns the local IP.
@@ -154,6 +190,20 @@ func (b *BaseTunnel) ServerIP() string {
 	return b.ServerIPAddr
 }
 
+// MTU returns the interface MTU.
+func (b *BaseTunnel) MTU() int {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+	return b.mtu
+}
+
+// SetMTU records the interface MTU.
+func (b *BaseTunnel) SetMTU(mtu int) {
+	b.stateMu.Lock()
+	b.mtu = mtu
+	b.stateMu.Unlock()
+}
+
 // Close closes the state changes channel. Safe to call multiple times.
 func (b *BaseTunnel) Close() {
 	b.closeChanOnce.Do(func() {
@@ -0,0 +1,19 @@
+package protocols
+
+import "net/netip"
+
+// PushedConfig is network configuration sent by the VPN server.
+type PushedConfig struct {
+	Routes        []netip.Prefix // networks to route through the tunnel
+	DefaultRoute  bool           // route all IPv4 traffic through the tunnel
+	DefaultRoute6 bool           // route all IPv6 traffic through the tunnel
+	DNSServers    []netip.Addr
+	DNSDomains    []string
+}
+
+// PushReceiver is implemented by tunnels whose server pushes routes and DNS
+// settings, such as OpenVPN.
+type PushReceiver interface {
+	// Pushed returns the configuration received for the current session.
+	Pushed() PushedConfig
+}
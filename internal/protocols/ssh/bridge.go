@@ -1,17 +1,55 @@
 package ssh
 
 import (
+	"bufio"
 	"io"
+
+	tunpkg "github.com/user/vpn-client/internal/tun"
 )
 
+// maxWriteBatch caps how many packets from the server are written to the
+// adapter at once.
+const maxWriteBatch = 128
+
+// bridgeReadBuffer is the size of the buffer frames from the server are
+// read through.
+const bridgeReadBuffer = 256 * 1024
+
+// packetBufs returns n buffers holding one packet each after the adapter
+// headroom.
+func packetBufs(n int) [][]byte {
+	bufs := make([][]byte, n)
+	for i := range bufs {
+		bufs[i] = make([]byte, tunpkg.Offset+65535)
+	}
+	return bufs
+}
+
+// countSent adds packets sent to the server to the statistics.
+func (t *Tunnel) countSent(packets, bytes int) {
+	t.packetsSent.Add(uint64(packets))
+	t.bytesSent.Add(uint64(bytes))
+}
+
+// countReceived adds packets received from the server to the statistics.
+func (t *Tunnel) countReceived(packets, bytes int) {
+	t.packetsRecv.Add(uint64(packets))
+	t.bytesReceived.Add(uint64(bytes))
+}
+
 // startPacketBridge bridges packets between local TUN and remote SSH tunnel.
+// Packets are framed with a big-endian uint16 length. Every batch read from
+// the adapter goes to SSH in one write, and frames already buffered from
+// SSH are written to the adapter together.
 func (t *Tunnel) startPacketBridge(stdin io.WriteCloser, stdout io.Reader) {
 	// Read from TUN, write length-prefixed packets to SSH
 	t.wg.Add(1)
 	go func() {
 		defer t.wg.Done()
-		buf := make([]byte, 65535)
-		lenBuf := make([]byte, 2)
+		batch := t.adapter.BatchSize()
+		bufs := packetBufs(batch)
+		sizes := make([]int, batch)
+		out := make([]byte, 0, 64*1024)
 		for {
 			select {
 			case <-t.stopCh:
@@ -19,24 +57,30 @@ func (t *Tunnel) startPacketBridge(stdin io.WriteCloser, stdout io.Reader) {
 			default:
 			}
 
-			n, err := t.adapter.Read(buf, 0)
+			n, err := t.adapter.ReadBatch(bufs, sizes, tunpkg.Offset)
 			if err != nil {
 				continue
 			}
 
-			if n > 0 {
-				// Write length prefix (big-endian uint16)
-				lenBuf[0] = byte(n >> 8)
-				lenBuf[1] = byte(n)
-				if _, err := stdin.Write(lenBuf); err != nil {
-					return
-				}
-				if _, err := stdin.Write(buf[:n]); err != nil {
-					return
+			out = out[:0]
+			packets, bytes := 0, 0
+			for i, size := range sizes[:n] {
+				if size == 0 {
+					continue
 				}
-				t.Statistics.BytesSent += uint64(n)
-				t.Statistics.PacketsSent++
+				// Length prefix (big-endian uint16), then the packet
+				out = append(out, byte(size>>8), byte(size))
+				out = append(out, bufs[i][tunpkg.Offset:tunpkg.Offset+size]...)
+				packets++
+				bytes += size
 			}
+			if packets == 0 {
+				continue
+			}
+			if _, err := stdin.Write(out); err != nil {
+				return
+			}
+			t.countSent(packets, bytes)
 		}
 	}()
 
@@ -44,8 +88,10 @@ func (t *Tunnel) startPacketBridge(stdin io.WriteCloser, stdout io.Reader) {
 	t.wg.Add(1)
 	go func() {
 		defer t.wg.Done()
+		r := bufio.NewReaderSize(stdout, bridgeReadBuffer)
+		bufs := packetBufs(maxWriteBatch)
+		batch := make([][]byte, 0, maxWriteBatch)
 		lenBuf := make([]byte, 2)
-		pktBuf := make([]byte, 65535)
 		for {
 			select {
 			case <-t.stopCh:
@@ -53,26 +99,31 @@ func (t *Tunnel) startPacketBridge(stdin io.WriteCloser, stdout io.Reader) {
 			default:
 			}
 
-			// Read length prefix
-			if _, err := io.ReadFull(stdout, lenBuf); err != nil {
-				return
-			}
-			pktLen := int(lenBuf[0])<<8 | int(lenBuf[1])
-			if pktLen > len(pktBuf) || pktLen == 0 {
-				continue
-			}
+			// Wait for one packet, then take the ones already buffered
+			batch = batch[:0]
+			bytes := 0
+			for len(batch) < maxWriteBatch && (len(batch) == 0 || r.Buffered() >= len(lenBuf)) {
+				if _, err := io.ReadFull(r, lenBuf); err != nil {
+					return
+				}
+				pktLen := int(lenBuf[0])<<8 | int(lenBuf[1])
+				if pktLen == 0 {
+					continue
+				}
 
-			// Read packet data
-			if _, err := io.ReadFull(stdout, pktBuf[:pktLen]); err != nil {
-				return
+				pkt := bufs[len(batch)][:tunpkg.Offset+pktLen]
+				if _, err := io.ReadFull(r, pkt[tunpkg.Offset:]); err != nil {
+					return
+				}
+				batch = append(batch, pkt)
+				bytes += pktLen
 			}
 
 			// Write to TUN
-			if _, err := t.adapter.Write(pktBuf[:pktLen], 0); err != nil {
+			if _, err := t.adapter.WriteBatch(batch, tunpkg.Offset); err != nil {
 				continue
 			}
-			t.Statistics.BytesReceived += uint64(pktLen)
-			t.Statistics.PacketsRecv++
+			t.countReceived(len(batch), bytes)
 		}
 	}()
 }
@@ -0,0 +1,181 @@
+package ssh
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+)
+
+// journalDir holds one journal per script-mode session on the server,
+// relative to the login directory. A journal lists what the session
+// changed, one "<kind> <value>" line each:
+//
+//	pid 1234          process forwarding packets (sudo or python3)
+//	boot 6f1c...      boot ID of the server when the session started
+//	start 83412       start time of the process (field 22 of /proc/PID/stat)
+//	nat 10.0.0.2      MASQUERADE source the session added
+//	tun tun0          device the session created
+const journalDir = ".cache/vpn-client/sessions"
+
+// lockDir holds the client address locks of the sessions of all users and
+// the host-wide "forward" file with the ip_forward value from before the
+// first session enabled it.
+const lockDir = "/run/vpn-client"
+
+// revertTimeout bounds reverting on Stop, so a dead server cannot hang it.
+const revertTimeout = 10 * time.Second
+
+// newJournal returns a fresh journal name for a session.
+func newJournal() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// journalPrologue returns the shell lines creating the session journal in
+// $J. It is created as the login user so the root helper only appends.
+func journalPrologue(name string) string {
+	return fmt.Sprintf(`J="$HOME/%s/%s"
+mkdir -p "${J%%/*}"
+{
+  echo "pid $$"
+  echo "boot $(cat /proc/sys/kernel/random/boot_id 2>/dev/null)"
+  echo "start $(sed 's/.*) //' /proc/$$/stat 2>/dev/null | cut -d' ' -f20)"
+} > "$J"
+`, journalDir, name)
+}
+
+// revertScript returns a shell script reverting the journals matching
+// pattern. Journals of sessions still running are skipped unless force is
+// set, in which case their process is killed first. A session counts as
+// running only if its process exists with the recorded start time in the
+// same boot, so a reused PID or a reboot does not keep a journal forever.
+// ip_forward is host-wide, so it is restored only once no session of any
+// user holds an address lock; flock(1) is needed to tell, and without it
+// forwarding stays on.
+func revertScript(sudo, pattern string, force bool) string {
+	forceFlag := 0
+	if force {
+		forceFlag = 1
+	}
+	return fmt.Sprintf(`S="%[1]s"
+L="%[5]s"
+cd "$HOME/%[2]s" 2>/dev/null || exit 0
+for j in %[3]s; do
+  [ -f "$j" ] || continue
+  pid=$(sed -n 's/^pid //p' "$j")
+  boot=$(sed -n 's/^boot //p' "$j")
+  start=$(sed -n 's/^start //p' "$j")
+  alive=0
+  if [ -n "$pid" ] && [ -d "/proc/$pid" ]; then
+    alive=1
+    if [ -n "$boot" ] && { [ "$boot" != "$(cat /proc/sys/kernel/random/boot_id 2>/dev/null)" ] ||
+      [ "$start" != "$(sed 's/.*) //' "/proc/$pid/stat" 2>/dev/null | cut -d' ' -f20)" ]; }; then
+      alive=0
+    fi
+  fi
+  if [ $alive = 1 ]; then
+    [ %[4]d = 1 ] || continue
+    $S kill "$pid" 2>/dev/null
+    sleep 1
+  fi
+  while read kind arg; do
+    case "$kind" in
+      tun) $S ip tuntap del dev "$arg" mode tun 2>/dev/null ;;
+      nat) $S iptables -t nat -D POSTROUTING -s "$arg" -j MASQUERADE 2>/dev/null ;;
+    esac
+  done < "$j"
+  # Journals of older helpers kept the ip_forward value themselves
+  fwd=$(sed -n 's/^forward //p' "$j")
+  if [ -n "$fwd" ] && [ ! -e "$L/forward" ]; then
+    echo "$fwd" | $S tee "$L/forward" >/dev/null
+  fi
+  echo "$j:" $(grep -Ev '^(pid|boot|start) ' "$j")
+  rm -f "$j"
+done
+if [ -f "$L/forward" ]; then
+  busy=0
+  for l in "$L"/addr-*.lock; do
+    [ -e "$l" ] || continue
+    flock -n "$l" true 2>/dev/null || { busy=1; break; }
+  done
+  if [ $busy = 0 ]; then
+    $S sysctl -q -w net.ipv4.ip_forward="$(cat "$L/forward")" && $S rm -f "$L/forward"
+  fi
+fi
+`, sudo, journalDir, pattern, forceFlag, lockDir)
+}
+
+// revertRemote undoes the changes of the current session. It runs after
+// the session is closed, while the client is still connected.
+func (t *Tunnel) revertRemote() {
+	if t.journal == "" || t.client == nil {
+		return
+	}
+	journal := t.journal
+	t.journal = ""
+
+	done := make(chan error, 1)
+	go func() {
+		out, err := t.runRemote(revertScript(t.sudo(), journal, true))
+		if err == nil && strings.TrimSpace(out) != "" {
+			logger.Info("SSH: reverted remote changes: %s", strings.TrimSpace(out))
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			logger.Warning("SSH: failed to revert remote changes, run `vpn-client ssh cleanup-remote`: %v", err)
+		}
+	case <-time.After(revertTimeout):
+		logger.Warning("SSH: reverting remote changes timed out, run `vpn-client ssh cleanup-remote`")
+	}
+}
+
+// revertStale undoes changes left by sessions of this user that ended
+// without a clean Stop, e.g. before a reconnect or after a crash.
+func (t *Tunnel) revertStale() {
+	out, err := t.runRemote(revertScript(t.sudo(), "*", false))
+	if err != nil {
+		logger.Warning("SSH: failed to revert stale remote changes: %v", err)
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line != "" {
+			logger.Info("SSH: reverted stale session %s", line)
+		}
+	}
+}
+
+// CleanupRemote connects to the server in cfg and reverts the changes of
+// all script-mode sessions of its user. Sessions still running are stopped
+// unless onlyStale is set. It returns one line per reverted session.
+func CleanupRemote(cfg *config.SSH, onlyStale bool, prompt protocols.PromptFunc) ([]string, error) {
+	t := New(cfg, &config.Interface{})
+	t.prompt = prompt
+	defer t.cleanup()
+
+	client, err := t.dial(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH server: %w", err)
+	}
+	t.client = client
+
+	out, err := t.runRemote(revertScript(t.sudo(), "*", !onlyStale))
+	if err != nil {
+		return nil, fmt.Errorf("failed to revert remote changes: %w", err)
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
+	"strings"
 	"sync"
 	"time"
 
-	"golang.org/x/crypto/ssh"
-
+	"github.com/user/vpn-client/internal/config"
 	"github.com/user/vpn-client/internal/logger"
 	"github.com/user/vpn-client/internal/protocols"
 	tunpkg "github.com/user/vpn-client/internal/tun"
@@ -28,56 +28,40 @@ func (t *Tunnel) Start(ctx context.Context) error {
 	t.ctx, t.cancel = context.WithCancel(ctx)
 	t.stopCh = make(chan struct{})
 
-	// Resolve server IP
+	// Resolve the directly connected host: the first jump host, if any
 	serverAddr := fmt.Sprintf("%s:%d", t.cfg.Host, t.cfg.Port)
-	ips, err := net.LookupIP(t.cfg.Host)
+	firstHop := t.firstHopHost()
+	ips, err := net.LookupIP(firstHop)
 	if err == nil && len(ips) > 0 {
 		t.ServerIPAddr = ips[0].String()
 	} else {
-		t.ServerIPAddr = t.cfg.Host
+		t.ServerIPAddr = firstHop
 	}
 	logger.Info("Resolved server IP: %s", t.ServerIPAddr)
 	fmt.Printf("SSH LOG: Resolved server IP: %s\n", t.ServerIPAddr)
 
-	// Prepare SSH client config
-	sshConfig, err := t.buildSSHConfig()
-	if err != nil {
-		t.SetState(protocols.StateError, "Failed to build SSH config", err)
-		return fmt.Errorf("failed to build SSH config: %w", err)
-	}
-	logger.Debug("SSH config built successfully")
-	fmt.Printf("SSH LOG: SSH config built successfully\n")
-
-	// Connect to SSH server with TCP keepalive
-	dialer := net.Dialer{Timeout: 30 * time.Second}
-	tcpConn, err := dialer.DialContext(ctx, "tcp", serverAddr)
+	// Connect through the jump hosts and authenticate
+	t.client, err = t.dial(ctx)
 	if err != nil {
+		t.cleanup()
 		t.SetState(protocols.StateError, "Failed to connect to SSH server", err)
 		return fmt.Errorf("failed to connect to SSH server: %w", err)
 	}
+	logger.Connection("Connected to SSH server at %s", serverAddr)
+	fmt.Printf("SSH LOG: Connected to SSH server at %s\n", serverAddr)
 
-	// Enable TCP-level keepalive on the socket
-	if tc, ok := tcpConn.(*net.TCPConn); ok {
-		_ = tc.SetKeepAlive(true)
-		_ = tc.SetKeepAlivePeriod(10 * time.Second)
-		logger.Debug("TCP keepalive enabled (10s)")
+	if t.proxyCfg != nil {
+		return t.startProxy()
 	}
 
-	// SSH handshake over the established TCP connection
-	sshConn, chans, reqs, err := ssh.NewClientConn(tcpConn, serverAddr, sshConfig)
-	if err != nil {
-		tcpConn.Close()
-		t.SetState(protocols.StateError, "SSH handshake failed", err)
-		return fmt.Errorf("SSH handshake failed: %w", err)
-	}
-	t.client = ssh.NewClient(sshConn, chans, reqs)
-	logger.Connection("Connected to SSH server at %s", serverAddr)
-	fmt.Printf("SSH LOG: Connected to SSH server at %s\n", serverAddr)
+	// Packets ride the SSH TCP stream, which segments them itself, so the
+	// path MTU does not apply and mtu: auto means the default.
+	t.SetMTU(t.ifaceCfg.MTU.Value())
 
 	// Create TUN adapter locally
 	t.adapter, err = tunpkg.New(&tunpkg.Config{
 		Name:   t.ifaceCfg.Name,
-		MTU:    t.ifaceCfg.MTU,
+		MTU:    t.MTU(),
 		Metric: t.ifaceCfg.Metric,
 	})
 	if err != nil {
@@ -94,7 +78,7 @@ func (t *Tunnel) Start(ctx context.Context) error {
 	logger.Info("TUN adapter created: %s", t.ifaceCfg.Name)
 	fmt.Printf("SSH LOG: TUN adapter created: %s\n", t.ifaceCfg.Name)
 
-	// Configure adapter IP - use local_tun_addr or default
+	// Client address - use local_tun_addr or default
 	localAddr := t.cfg.LocalTunAddr
 	if localAddr == "" {
 		localAddr = "10.0.0.2/24"
@@ -104,6 +88,32 @@ func (t *Tunnel) Start(ctx context.Context) error {
 		localAddr = localAddr + "/24"
 	}
 
+	if t.cfg.TunnelMode == config.SSHTunnelNative {
+		// Standard tun@openssh.com channel, no remote commands
+		if err := t.openTunChannel(); err != nil {
+			t.cleanup()
+			t.SetState(protocols.StateError, "Failed to open tun channel", err)
+			return fmt.Errorf("failed to open tun channel: %w", err)
+		}
+	} else {
+		// Start the forwarder on the remote side over an exec session. The
+		// server may pick another client address if ours is taken.
+		client, err := t.requestTunnel()
+		if err != nil {
+			t.cleanup()
+			t.SetState(protocols.StateError, "Failed to request tunnel", err)
+			return fmt.Errorf("failed to request tunnel: %w", err)
+		}
+		logger.Info("Tunnel requested on remote side")
+		fmt.Printf("SSH LOG: Tunnel requested on remote side\n")
+
+		if ip, bits, _ := strings.Cut(localAddr, "/"); client != ip {
+			logger.Info("SSH: server assigned client address %s", client)
+			localAddr = client + "/" + bits
+		}
+	}
+
+	// Configure adapter IP
 	if err := t.adapter.Configure(localAddr); err != nil {
 		t.cleanup()
 		t.SetState(protocols.StateError, "Failed to configure adapter", err)
@@ -125,26 +135,6 @@ func (t *Tunnel) Start(ctx context.Context) error {
 	}
 	t.GatewayIPAddr, _ = netip.ParseAddr(remoteIP)
 
-	// Create SSH session for tunnel
-	t.session, err = t.client.NewSession()
-	if err != nil {
-		t.cleanup()
-		t.SetState(protocols.StateError, "Failed to create SSH session", err)
-		return fmt.Errorf("failed to create SSH session: %w", err)
-	}
-	logger.Debug("SSH session created")
-
-	// Request TUN device on remote side
-	// This requires PermitTunnel=yes in server sshd_config
-	err = t.requestTunnel()
-	if err != nil {
-		t.cleanup()
-		t.SetState(protocols.StateError, "Failed to request tunnel", err)
-		return fmt.Errorf("failed to request tunnel: %w", err)
-	}
-	logger.Info("Tunnel requested on remote side")
-	fmt.Printf("SSH LOG: Tunnel requested on remote side\n")
-
 	// Bring adapter up
 	if err := t.adapter.Up(); err != nil {
 		t.cleanup()
@@ -165,6 +155,8 @@ func (t *Tunnel) Start(ctx context.Context) error {
 	logger.Connection("SSH tunnel fully established")
 	fmt.Printf("SSH LOG: SSH tunnel fully established\n")
 
+	t.startForwards()
+
 	// Start keepalive
 	go t.keepalive()
 
@@ -180,6 +172,10 @@ func (t *Tunnel) Stop() error {
 		return nil
 	}
 
+	// A reconnect finds the server unreachable; its leftovers are reverted
+	// once connected again
+	clean := t.State() != protocols.StateReconnecting
+
 	t.SetState(protocols.StateDisconnecting, "Stopping SSH tunnel", nil)
 
 	if t.cancel != nil {
@@ -189,6 +185,14 @@ func (t *Tunnel) Stop() error {
 	// Signal goroutines to stop (safe against double-close)
 	t.stopOnce.Do(func() { close(t.stopCh) })
 
+	// Close the forwarder session and undo its remote changes while the
+	// client is still connected
+	if clean && t.session != nil {
+		t.session.Close()
+		t.session = nil
+		t.revertRemote()
+	}
+
 	// Close connections FIRST to unblock goroutines waiting on I/O
 	// (adapter.Read, io.ReadFull on SSH stdout, etc.)
 	t.cleanup()
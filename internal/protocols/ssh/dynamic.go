@@ -0,0 +1,100 @@
+package ssh
+
+import (
+	"context"
+	"net"
+
+	"github.com/user/vpn-client/internal/connmon"
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+	"github.com/user/vpn-client/internal/proxy"
+)
+
+// startProxy starts the local proxies of proxy mode on the connected
+// client. Called by Start with t.mu held.
+func (t *Tunnel) startProxy() error {
+	t.proxy = proxy.New(&proxy.Config{
+		SOCKSListen: t.proxyCfg.SOCKSListen,
+		HTTPListen:  t.proxyCfg.HTTPListen,
+		Dial:        t.dialThrough,
+		Username:    t.proxyCfg.Username,
+		Password:    t.proxyCfg.Password,
+	})
+	if err := t.proxy.Start(); err != nil {
+		t.proxy = nil
+		t.cleanup()
+		t.SetState(protocols.StateError, "Failed to start proxy", err)
+		return err
+	}
+
+	t.SetState(protocols.StateConnected, "SSH proxy established", nil)
+	logger.Connection("SSH proxy established")
+
+	t.startForwards()
+
+	// Start keepalive
+	go t.keepalive()
+
+	return nil
+}
+
+// dialThrough opens a direct-tcpip channel to address through the server.
+func (t *Tunnel) dialThrough(ctx context.Context, network, address string) (net.Conn, error) {
+	client := t.GetClient()
+	if client == nil {
+		return nil, net.ErrClosed
+	}
+
+	// ssh.Client.Dial has no context; give up waiting when ctx ends
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		c, err := client.Dial(network, address)
+		done <- result{c, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, r.err
+		}
+		return &countingConn{Conn: connmon.TrackProxied("ssh-proxy", address, r.conn), t: t}, nil
+	case <-ctx.Done():
+		go func() {
+			if r := <-done; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// countingConn adds the traffic of a proxied connection to the tunnel
+// statistics.
+type countingConn struct {
+	net.Conn
+	t *Tunnel
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.t.bytesReceived.Add(uint64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.t.bytesSent.Add(uint64(n))
+	return n, err
+}
+
+// CloseWrite passes half-closes through to the channel.
+func (c *countingConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return c.Conn.Close()
+}
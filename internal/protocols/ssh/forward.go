@@ -0,0 +1,249 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/connmon"
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+	"github.com/user/vpn-client/internal/proxy"
+)
+
+// Port forward kinds, as in protocols.PortForward.
+const (
+	forwardLocal  = "local"
+	forwardRemote = "remote"
+)
+
+// forwardRetryMax caps the delay between attempts to re-open a listener.
+const forwardRetryMax = 30 * time.Second
+
+// portForward is one configured port forward and its current state.
+type portForward struct {
+	kind    string
+	listen  string
+	connect string
+
+	mu     sync.Mutex
+	ln     net.Listener
+	err    error
+	conns  map[net.Conn]net.Conn // accepted -> dialed
+	closed bool
+}
+
+func newPortForward(kind string, f config.SSHForward) *portForward {
+	return &portForward{
+		kind:    kind,
+		listen:  f.ListenAddr(),
+		connect: f.Connect,
+		conns:   make(map[net.Conn]net.Conn),
+	}
+}
+
+// startForwards starts the configured port forwards over the connected
+// client. Called by Start with t.mu held; cleanup stops them, so every
+// reconnect establishes them again.
+func (t *Tunnel) startForwards() {
+	var forwards []*portForward
+	for _, f := range t.cfg.Forwards.Local {
+		forwards = append(forwards, newPortForward(forwardLocal, f))
+	}
+	for _, f := range t.cfg.Forwards.Remote {
+		forwards = append(forwards, newPortForward(forwardRemote, f))
+	}
+	if len(forwards) == 0 {
+		return
+	}
+
+	t.fwdMu.Lock()
+	t.forwards = forwards
+	t.fwdMu.Unlock()
+
+	for _, f := range forwards {
+		t.wg.Add(1)
+		go t.keepForward(f, t.client, t.stopCh)
+	}
+}
+
+// stopForwards closes the listeners and connections of all forwards.
+func (t *Tunnel) stopForwards() {
+	t.fwdMu.Lock()
+	defer t.fwdMu.Unlock()
+
+	for _, f := range t.forwards {
+		f.close()
+	}
+	t.forwards = nil
+}
+
+// PortForwards returns the state of the configured port forwards.
+func (t *Tunnel) PortForwards() []protocols.PortForward {
+	t.fwdMu.Lock()
+	defer t.fwdMu.Unlock()
+
+	var list []protocols.PortForward
+	for _, f := range t.forwards {
+		list = append(list, f.status())
+	}
+	return list
+}
+
+// keepForward opens the listener of f and serves it, opening it again
+// with backoff whenever it fails, until stop is closed.
+func (t *Tunnel) keepForward(f *portForward, client *ssh.Client, stop <-chan struct{}) {
+	defer t.wg.Done()
+	defer logger.Recover("sshForward")
+
+	delay := time.Second
+	for {
+		ln, err := f.open(client)
+		if err == nil {
+			if !f.setListener(ln) {
+				return
+			}
+			logger.Info("SSH: %s forward %s -> %s established", f.kind, f.listen, f.connect)
+			delay = time.Second
+			f.serve(ln, client)
+			err = fmt.Errorf("listener closed")
+		}
+		if !f.setError(err) {
+			return
+		}
+		logger.Warning("SSH: %s forward %s -> %s failed, retrying in %s: %v", f.kind, f.listen, f.connect, delay, err)
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, forwardRetryMax)
+	}
+}
+
+// open listens on the local side for a local forward and on the server
+// for a remote one.
+func (f *portForward) open(client *ssh.Client) (net.Listener, error) {
+	if f.kind == forwardRemote {
+		return client.Listen("tcp", f.listen)
+	}
+	return net.Listen("tcp", f.listen)
+}
+
+// dial connects to the target from the other side of the forward.
+func (f *portForward) dial(client *ssh.Client) (net.Conn, error) {
+	if f.kind == forwardRemote {
+		return net.DialTimeout("tcp", f.connect, 10*time.Second)
+	}
+	c, err := client.Dial("tcp", f.connect)
+	if err != nil {
+		return nil, err
+	}
+	return connmon.TrackProxied("ssh-forward", f.connect, c), nil
+}
+
+// serve accepts connections until the listener fails or is closed.
+func (f *portForward) serve(ln net.Listener, client *ssh.Client) {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer logger.Recover("sshForwardConn")
+
+			target, err := f.dial(client)
+			if err != nil {
+				logger.Warning("SSH: %s forward to %s failed: %v", f.kind, f.connect, err)
+				c.Close()
+				return
+			}
+			if !f.track(c, target) {
+				c.Close()
+				target.Close()
+				return
+			}
+			defer f.untrack(c)
+
+			logger.Debug("SSH: %s forward %s -> %s", f.kind, c.RemoteAddr(), f.connect)
+			proxy.Relay(c, target)
+		}()
+	}
+}
+
+func (f *portForward) setListener(ln net.Listener) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		ln.Close()
+		return false
+	}
+	f.ln, f.err = ln, nil
+	return true
+}
+
+func (f *portForward) setError(err error) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ln, f.err = nil, err
+	return !f.closed
+}
+
+func (f *portForward) track(c, target net.Conn) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return false
+	}
+	f.conns[c] = target
+	return true
+}
+
+func (f *portForward) untrack(c net.Conn) {
+	f.mu.Lock()
+	target, ok := f.conns[c]
+	delete(f.conns, c)
+	f.mu.Unlock()
+
+	c.Close()
+	if ok {
+		target.Close()
+	}
+}
+
+// close stops the forward: the listener and all its connections.
+func (f *portForward) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	if f.ln != nil {
+		f.ln.Close()
+	}
+	for c, target := range f.conns {
+		c.Close()
+		target.Close()
+	}
+}
+
+func (f *portForward) status() protocols.PortForward {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := protocols.PortForward{
+		Kind:    f.kind,
+		Listen:  f.listen,
+		Connect: f.connect,
+		Active:  f.ln != nil,
+		Conns:   len(f.conns),
+	}
+	if f.ln == nil && f.err != nil {
+		s.Error = f.err.Error()
+	}
+	return s
+}
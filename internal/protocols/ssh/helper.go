@@ -0,0 +1,149 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/sshhelper"
+	"github.com/user/vpn-client/resources/helperbin"
+)
+
+// helperCacheDir keeps uploaded helpers on the server, relative to the
+// login directory. File names carry the checksum, so clients of different
+// versions never overwrite each other's helper.
+const helperCacheDir = ".cache/vpn-client"
+
+// startHelper installs the Go helper on the server and starts it. It
+// returns the client address the helper allocated.
+func (t *Tunnel) startHelper(clientAddr, serverIP string) (string, error) {
+	path, err := t.installHelper()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := journalPrologue(t.journal) + fmt.Sprintf(`exec %s"$HOME/%s" -local %s -peer %s -nat -journal "$J"`,
+		t.sudo(), path, serverIP, clientAddr)
+	var client string
+	err = t.startRemote(cmd, func(line string) error {
+		tun, addr, err := sshhelper.ParseReady(line)
+		if err != nil {
+			return err
+		}
+		logger.Info("SSH: remote helper is forwarding on %s", tun)
+		client = addr
+		return nil
+	})
+	return client, err
+}
+
+// installHelper returns the path of the helper for the server's
+// architecture, uploading it over SFTP unless a copy with the same
+// checksum is already cached there.
+func (t *Tunnel) installHelper() (string, error) {
+	out, err := t.runRemote("uname -sm")
+	if err != nil {
+		return "", fmt.Errorf("failed to detect server architecture: %w", err)
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 || fields[0] != "Linux" {
+		return "", fmt.Errorf("unsupported server system %q", strings.TrimSpace(out))
+	}
+	arch, ok := sshhelper.Arch(fields[1])
+	if !ok {
+		return "", fmt.Errorf("unsupported server architecture %q", fields[1])
+	}
+	bin := helperbin.Binary(arch)
+	if bin == nil {
+		return "", fmt.Errorf("helper for linux/%s is not included in this build", arch)
+	}
+
+	sum := sha256.Sum256(bin)
+	digest := hex.EncodeToString(sum[:])
+	path := helperCacheDir + "/vpn-ssh-helper-" + digest[:16]
+
+	if t.remoteChecksum(path) == digest {
+		logger.Debug("SSH: using cached helper %s", path)
+		return path, nil
+	}
+	if err := t.uploadHelper(path, bin); err != nil {
+		return "", err
+	}
+	// Servers without sha256sum are trusted on the SFTP write alone
+	if got := t.remoteChecksum(path); got != "" && got != digest {
+		return "", fmt.Errorf("helper checksum mismatch after upload: got %s", got)
+	}
+	return path, nil
+}
+
+// uploadHelper writes bin to path over SFTP. It is written under a
+// temporary name first so a concurrent client never runs a partial file.
+func (t *Tunnel) uploadHelper(path string, bin []byte) error {
+	client, err := sftp.NewClient(t.client)
+	if err != nil {
+		return fmt.Errorf("failed to start SFTP: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.MkdirAll(helperCacheDir); err != nil {
+		return fmt.Errorf("failed to create %s: %w", helperCacheDir, err)
+	}
+
+	tmp := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+	f, err := client.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to upload helper: %w", err)
+	}
+	if _, err := f.Write(bin); err != nil {
+		f.Close()
+		client.Remove(tmp)
+		return fmt.Errorf("failed to upload helper: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		client.Remove(tmp)
+		return fmt.Errorf("failed to upload helper: %w", err)
+	}
+	if err := client.Chmod(tmp, 0755); err != nil {
+		client.Remove(tmp)
+		return fmt.Errorf("failed to make helper executable: %w", err)
+	}
+
+	client.Remove(path) // plain SFTP rename does not replace
+	if err := client.Rename(tmp, path); err != nil {
+		client.Remove(tmp)
+		return fmt.Errorf("failed to install helper: %w", err)
+	}
+	logger.Info("SSH: uploaded helper to ~/%s (%d bytes)", path, len(bin))
+	return nil
+}
+
+// remoteChecksum returns the SHA-256 of a file on the server, or "" if it
+// is missing or cannot be hashed.
+func (t *Tunnel) remoteChecksum(path string) string {
+	out, err := t.runRemote(fmt.Sprintf(`sha256sum "$HOME/%s" 2>/dev/null`, path))
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// runRemote runs a short command in its own session and returns its output.
+func (t *Tunnel) runRemote(cmd string) (string, error) {
+	session, err := t.client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	defer session.Close()
+
+	out, err := session.Output(cmd)
+	return string(out), err
+}
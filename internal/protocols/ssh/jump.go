@@ -0,0 +1,107 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/logger"
+)
+
+// hop is one SSH server on the way to the VPN endpoint.
+type hop struct {
+	addr string
+	user string
+	auth *config.SSHAuth
+}
+
+// hops returns the jump hosts followed by the server.
+func (t *Tunnel) hops() []hop {
+	var hops []hop
+	for i := range t.cfg.JumpHosts {
+		j := &t.cfg.JumpHosts[i]
+		port, user := j.Port, j.User
+		if port == 0 {
+			port = 22
+		}
+		if user == "" {
+			user = t.cfg.User
+		}
+		hops = append(hops, hop{
+			addr: net.JoinHostPort(j.Host, strconv.Itoa(port)),
+			user: user,
+			auth: &j.SSHAuth,
+		})
+	}
+	return append(hops, hop{
+		addr: fmt.Sprintf("%s:%d", t.cfg.Host, t.cfg.Port),
+		user: t.cfg.User,
+		auth: &t.cfg.SSHAuth,
+	})
+}
+
+// firstHopHost returns the host the client connects to directly.
+func (t *Tunnel) firstHopHost() string {
+	if len(t.cfg.JumpHosts) > 0 {
+		return t.cfg.JumpHosts[0].Host
+	}
+	return t.cfg.Host
+}
+
+// dial connects to the server through the jump host chain: the first hop
+// over TCP, every further hop through a channel of the previous one. The
+// intermediate clients are kept in t.jumps.
+func (t *Tunnel) dial(ctx context.Context) (*ssh.Client, error) {
+	var prev *ssh.Client
+	for i, h := range t.hops() {
+		sshConfig, releaseAuth, err := t.buildSSHConfig(h.addr, h.user, h.auth)
+		if err != nil {
+			return nil, err
+		}
+
+		var conn net.Conn
+		if prev == nil {
+			dialer := net.Dialer{Timeout: 30 * time.Second}
+			conn, err = dialer.DialContext(ctx, "tcp", h.addr)
+			if tc, ok := conn.(*net.TCPConn); ok {
+				// Enable TCP-level keepalive on the socket
+				_ = tc.SetKeepAlive(true)
+				_ = tc.SetKeepAlivePeriod(10 * time.Second)
+			}
+		} else {
+			conn, err = prev.Dial("tcp", h.addr)
+		}
+		if err != nil {
+			releaseAuth()
+			return nil, fmt.Errorf("failed to connect to %s: %w", h.addr, err)
+		}
+
+		sshConn, chans, reqs, err := ssh.NewClientConn(conn, h.addr, sshConfig)
+		releaseAuth()
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("SSH handshake with %s failed: %w", h.addr, err)
+		}
+		client := ssh.NewClient(sshConn, chans, reqs)
+
+		if i < len(t.cfg.JumpHosts) {
+			logger.Info("Connected to jump host %s", h.addr)
+			t.jumps = append(t.jumps, client)
+		}
+		prev = client
+	}
+	return prev, nil
+}
+
+// closeJumps closes the jump host connections, last hop first.
+func (t *Tunnel) closeJumps() {
+	for i := len(t.jumps) - 1; i >= 0; i-- {
+		t.jumps[i].Close()
+	}
+	t.jumps = nil
+}
@@ -4,28 +4,122 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"net/netip"
+	"strings"
 
+	"github.com/user/vpn-client/internal/config"
 	"github.com/user/vpn-client/internal/logger"
 )
 
 // requestTunnel sets up the remote TUN device and starts packet forwarding.
-func (t *Tunnel) requestTunnel() error {
-	// Set up the remote TUN interface via exec
+// The Go helper is used when it can be installed on the server; otherwise
+// (or with remote_helper: python) the Python script is run. It returns the
+// client address the server allocated for this session.
+func (t *Tunnel) requestTunnel() (string, error) {
 	// The server needs to allow the user to create TUN devices
+	clientAddr, serverIP := t.tunAddrs()
 
-	tunNum := 0 // Use tun0 device
+	// Revert what crashed sessions left behind, then journal this one
+	t.revertStale()
+	t.journal = newJournal()
 
-	stdin, err := t.session.StdinPipe()
+	if t.cfg.RemoteHelper != config.SSHHelperPython {
+		client, err := t.startHelper(clientAddr, serverIP)
+		if err == nil {
+			return checkClientAddr(client)
+		}
+		if t.cfg.RemoteHelper == config.SSHHelperGo {
+			return "", fmt.Errorf("failed to start remote helper: %w", err)
+		}
+		logger.Warning("SSH: Go helper unavailable, falling back to Python: %v", err)
+
+		// Undo whatever the helper set up before it failed
+		if t.session != nil {
+			t.session.Close()
+			t.session = nil
+		}
+		t.revertRemote()
+		t.journal = newJournal()
+	}
+
+	var client string
+	err := t.startRemote(t.pythonCommand(clientAddr, serverIP), func(line string) error {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != "READY" {
+			return fmt.Errorf("unexpected signal from remote: %s", line)
+		}
+		logger.Info("SSH: remote script is forwarding on %s", fields[1])
+		client = fields[2]
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return checkClientAddr(client)
+}
+
+// checkClientAddr validates the client address reported by the server.
+func checkClientAddr(addr string) (string, error) {
+	if _, err := netip.ParseAddr(addr); err != nil {
+		return "", fmt.Errorf("server assigned invalid client address %q", addr)
+	}
+	return addr, nil
+}
+
+// tunAddrs returns the preferred client address with the prefix of the
+// pool the server may pick another one from, and the server address.
+func (t *Tunnel) tunAddrs() (clientAddr, serverIP string) {
+	clientAddr = t.cfg.LocalTunAddr
+	if clientAddr == "" {
+		clientAddr = "10.0.0.2"
+	}
+	if !containsSlash(clientAddr) {
+		clientAddr += "/24"
+	}
+
+	serverIP = t.cfg.RemoteTunAddr
+	if serverIP == "" {
+		serverIP = "10.0.0.1"
+	}
+	if idx := indexOf(serverIP, '/'); idx > 0 {
+		serverIP = serverIP[:idx]
+	}
+	return clientAddr, serverIP
+}
+
+// sudo returns the command prefix for root privileges on the server.
+func (t *Tunnel) sudo() string {
+	// Use sudo only if user is not root
+	if t.cfg.User == "root" {
+		return ""
+	}
+	return "sudo "
+}
+
+// startRemote runs cmd in a new exec session, waits for its ready line and
+// bridges packets over its stdin/stdout.
+func (t *Tunnel) startRemote(cmd string, ready func(line string) error) error {
+	if t.session != nil {
+		t.session.Close()
+	}
+	session, err := t.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	t.session = session
+	logger.Debug("SSH session created")
+
+	stdin, err := session.StdinPipe()
 	if err != nil {
 		return fmt.Errorf("failed to get stdin pipe: %w", err)
 	}
 
-	stdout, err := t.session.StdoutPipe()
+	stdout, err := session.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("failed to get stdout pipe: %w", err)
 	}
 
-	stderr, err := t.session.StderrPipe()
+	stderr, err := session.StderrPipe()
 	if err != nil {
 		return fmt.Errorf("failed to get stderr pipe: %w", err)
 	}
@@ -47,33 +141,65 @@ func (t *Tunnel) requestTunnel() error {
 
 	// DO NOT request PTY - it corrupts binary data!
 
-	// Get local and remote IPs for the tunnel
-	localIP := t.cfg.LocalTunAddr
-	if localIP == "" {
-		localIP = "10.0.0.2"
-	}
-	if idx := indexOf(localIP, '/'); idx > 0 {
-		localIP = localIP[:idx]
+	if err := session.Start(cmd); err != nil {
+		return fmt.Errorf("failed to start tunnel command: %w", err)
 	}
+	logger.Info("Tunnel setup command started on remote server")
 
-	remoteIP := t.cfg.RemoteTunAddr
-	if remoteIP == "" {
-		remoteIP = "10.0.0.1"
+	line, err := readLine(stdout)
+	if err != nil {
+		return fmt.Errorf("failed to read READY signal: %w", err)
 	}
-	if idx := indexOf(remoteIP, '/'); idx > 0 {
-		remoteIP = remoteIP[:idx]
+	if err := ready(line); err != nil {
+		return err
 	}
+	logger.Info("Remote TUN device ready")
+	fmt.Printf("SSH LOG: Remote TUN device ready\n")
 
-	// Use sudo only if user is not root
-	isRoot := t.cfg.User == "root"
-	sudoPrefix := ""
-	if !isRoot {
-		sudoPrefix = "sudo "
+	// Store stdin/stdout for packet forwarding
+	t.startPacketBridge(stdin, stdout)
+	logger.Debug("Packet bridge started")
+
+	return nil
+}
+
+// readLine reads one line byte by byte, so no packet data after it is
+// consumed.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for len(line) < 256 {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return strings.TrimRight(string(line), "\r"), nil
+		}
+		line = append(line, b[0])
 	}
+	return "", fmt.Errorf("line too long: %q", line)
+}
+
+// pythonCommand returns the shell command running the Python forwarder,
+// which allocates the address and TUN device itself.
+func (t *Tunnel) pythonCommand(clientAddr, serverIP string) string {
+	// Encode the Python script in base64 to avoid shell escaping issues
+	// and to skip Setenv (most servers reject it via AcceptEnv, and some
+	// close the channel on rejection, leaving the session in a broken state).
+	encodedScript := base64.StdEncoding.EncodeToString([]byte(pythonScript))
+
+	// sudo resets the environment, so the variables are passed via env
+	return fmt.Sprintf(`
+set -e
+%[4]sexec %[1]senv SERVER_IP=%[2]s CLIENT_ADDR=%[3]s JOURNAL="$J" python3 -u -c "$(echo '%[5]s' | base64 -d)"
+`, t.sudo(), serverIP, clientAddr, journalPrologue(t.journal), encodedScript)
+}
 
-	// Python script for TUN forwarding - creates TUN device itself
-	pythonScript := `
-import os, sys, select, fcntl, struct, signal, subprocess, time, errno
+// pythonScript forwards packets on servers without the Go helper. It
+// allocates addresses and devices like the helper does, so both can serve
+// clients on the same server.
+const pythonScript = `
+import os, sys, select, fcntl, struct, signal, subprocess, errno, ipaddress, itertools
 
 def handler(sig, frame):
     sys.exit(0)
@@ -83,63 +209,95 @@ signal.signal(signal.SIGINT, handler)
 TUNSETIFF = 0x400454ca
 IFF_TUN = 0x0001
 IFF_NO_PI = 0x1000
+LOCK_DIR = '/run/vpn-client'
+
+server_ip = os.environ.get('SERVER_IP', '10.0.0.1')
+client_addr = ipaddress.ip_interface(os.environ.get('CLIENT_ADDR', '10.0.0.2/24'))
+journal = os.environ.get('JOURNAL')
+
+def record(kind, value):
+    # Changes are reverted from the journal when the session ends
+    if journal:
+        with open(journal, 'a') as f:
+            f.write('{} {}\n'.format(kind, value))
 
-tun_base = os.environ.get('TUN_NAME', 'tun0')
-local_ip = os.environ.get('LOCAL_IP', '10.0.0.1')
-remote_ip = os.environ.get('REMOTE_IP', '10.0.0.2')
+def addresses_in_use():
+    out = subprocess.run(['ip', '-o', '-4', 'addr', 'show'], stdout=subprocess.PIPE,
+                         universal_newlines=True, check=True).stdout.split()
+    return {out[i + 1].split('/')[0] for i in range(len(out) - 1) if out[i] in ('inet', 'peer')}
 
-# Try to find an available TUN device (try up to 10 devices)
+# Pick the client address: the preferred one if free, else the next free
+# one of its network. The lock is held until the process exits.
+os.makedirs(LOCK_DIR, exist_ok=True)
+used = addresses_in_use()
+network = client_addr.network
+candidates = itertools.chain([client_addr.ip], itertools.islice(network.hosts(), 1024))
+client_ip = None
+for ip in candidates:
+    ip = str(ip)
+    if ip == server_ip or ip in used:
+        continue
+    lock_fd = os.open('{}/addr-{}.lock'.format(LOCK_DIR, ip), os.O_RDWR | os.O_CREAT, 0o644)
+    try:
+        fcntl.flock(lock_fd, fcntl.LOCK_EX | fcntl.LOCK_NB)
+    except OSError:
+        os.close(lock_fd)
+        continue
+    os.write(lock_fd, '{}\n'.format(os.getpid()).encode())
+    client_ip = ip
+    break
+if client_ip is None:
+    raise OSError(errno.EADDRINUSE, 'no free client address in {}'.format(network))
+
+with open('/proc/sys/net/ipv4/ip_forward') as f:
+    forward = f.read().strip()
+if forward != '1':
+    # Kept host-wide like the helper does: the client restores it once no
+    # session of any user holds an address lock
+    try:
+        fd = os.open(LOCK_DIR + '/forward', os.O_WRONLY | os.O_CREAT | os.O_EXCL, 0o644)
+        os.write(fd, (forward + '\n').encode())
+        os.close(fd)
+    except FileExistsError:
+        pass
+    with open('/proc/sys/net/ipv4/ip_forward', 'w') as f:
+        f.write('1\n')
+
+rule = ['POSTROUTING', '-s', client_ip, '-j', 'MASQUERADE']
+if subprocess.run(['iptables', '-t', 'nat', '-C'] + rule, stderr=subprocess.DEVNULL).returncode != 0:
+    subprocess.run(['iptables', '-t', 'nat', '-A'] + rule, check=True)
+    record('nat', client_ip)
+
+# Create the first free device named after the login user. Existing
+# devices are never touched: they may belong to other sessions.
+uid = os.environ.get('SUDO_UID') or str(os.getuid())
 tun_fd = None
-tun_name = None
-last_error = None
-
-for attempt in range(10):
-    if attempt == 0:
-        tun_name = tun_base
-    else:
-        # Extract base name and number, then try next number
-        base_num = int(tun_base.replace('tun', ''))
-        tun_name = 'tun{}'.format(base_num + attempt)
-    
-    # Cleanup any existing tun device completely
-    subprocess.run(['ip', 'link', 'set', tun_name, 'down'], stderr=subprocess.DEVNULL)
-    subprocess.run(['ip', 'addr', 'flush', 'dev', tun_name], stderr=subprocess.DEVNULL)
-    subprocess.run(['ip', 'tuntap', 'del', 'dev', tun_name, 'mode', 'tun'], stderr=subprocess.DEVNULL)
-    time.sleep(0.5)  # Increased wait time for kernel cleanup
-    
+for n in range(100):
+    tun_name = 'vpn{}-{}'.format(uid, n)
+    if os.path.exists('/sys/class/net/' + tun_name):
+        continue
+    fd = os.open('/dev/net/tun', os.O_RDWR)
     try:
-        # Open TUN control device and create interface
-        tun_fd = os.open('/dev/net/tun', os.O_RDWR)
-        ifr = struct.pack('16sH', tun_name.encode(), IFF_TUN | IFF_NO_PI)
-        fcntl.ioctl(tun_fd, TUNSETIFF, ifr)
-        break  # Success!
+        fcntl.ioctl(fd, TUNSETIFF, struct.pack('16sH', tun_name.encode(), IFF_TUN | IFF_NO_PI))
     except OSError as e:
-        last_error = e
-        if tun_fd is not None:
-            try:
-                os.close(tun_fd)
-            except:
-                pass
-            tun_fd = None
-        if e.errno != errno.EBUSY:
-            # If it's not a "busy" error, don't retry
-            raise
-        # Otherwise, try next device number
-
+        os.close(fd)
+        if e.errno in (errno.EBUSY, errno.EEXIST):
+            continue
+        raise
+    tun_fd = fd
+    break
 if tun_fd is None:
-    raise OSError(errno.EBUSY, 'All TUN devices busy, last error: {}'.format(last_error))
+    raise OSError(errno.EBUSY, 'no free TUN device for uid {}'.format(uid))
 
-# Log which device we're using
-sys.stderr.write('Using TUN device: {}\n'.format(tun_name))
+sys.stderr.write('Using TUN device: {}, client address {}\n'.format(tun_name, client_ip))
 sys.stderr.flush()
+record('tun', tun_name)
 
-# Configure the interface - flush any existing addresses first
-subprocess.run(['ip', 'addr', 'flush', 'dev', tun_name], stderr=subprocess.DEVNULL)
-subprocess.run(['ip', 'addr', 'add', local_ip, 'peer', remote_ip, 'dev', tun_name], check=True)
+subprocess.run(['ip', 'addr', 'add', server_ip, 'peer', client_ip, 'dev', tun_name], check=True)
 subprocess.run(['ip', 'link', 'set', tun_name, 'up'], check=True)
 
 # Signal that we're ready
-os.write(sys.stdout.fileno(), b'READY\n')
+os.write(sys.stdout.fileno(), 'READY {} {}\n'.format(tun_name, client_ip).encode())
 
 stdin_fd = sys.stdin.fileno()
 stdout_fd = sys.stdout.fileno()
@@ -168,41 +326,3 @@ while True:
                 pkt += chunk
             os.write(tun_fd, pkt)
 `
-
-	// Encode the Python script in base64 to avoid shell escaping issues
-	// and to skip Setenv (most servers reject it via AcceptEnv, and some
-	// close the channel on rejection, leaving the session in a broken state).
-	encodedScript := base64.StdEncoding.EncodeToString([]byte(pythonScript))
-
-	setupCmd := fmt.Sprintf(`
-set -e
-%[1]ssysctl -q -w net.ipv4.ip_forward=1
-%[1]siptables -t nat -C POSTROUTING -s %[4]s -j MASQUERADE 2>/dev/null || %[1]siptables -t nat -A POSTROUTING -s %[4]s -j MASQUERADE
-export TUN_NAME=tun%[2]d
-export LOCAL_IP=%[3]s
-export REMOTE_IP=%[4]s
-exec %[1]spython3 -u -c "$(echo '%[5]s' | base64 -d)"
-`, sudoPrefix, tunNum, remoteIP, localIP, encodedScript)
-
-	if err := t.session.Start(setupCmd); err != nil {
-		return fmt.Errorf("failed to start tunnel command: %w", err)
-	}
-	logger.Info("Tunnel setup command started on remote server")
-
-	// Wait for READY signal from Python
-	readyBuf := make([]byte, 6)
-	if _, err := io.ReadFull(stdout, readyBuf); err != nil {
-		return fmt.Errorf("failed to read READY signal: %w", err)
-	}
-	if string(readyBuf) != "READY\n" {
-		return fmt.Errorf("unexpected signal from remote: %s", string(readyBuf))
-	}
-	logger.Info("Remote TUN device ready")
-	fmt.Printf("SSH LOG: Remote TUN device ready\n")
-
-	// Store stdin/stdout for packet forwarding
-	t.startPacketBridge(stdin, stdout)
-	logger.Debug("Packet bridge started")
-
-	return nil
-}
@@ -0,0 +1,195 @@
+package ssh
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+	tunpkg "github.com/user/vpn-client/internal/tun"
+)
+
+// OpenSSH tunnel forwarding (ssh -w), see PROTOCOL in the OpenSSH sources.
+const (
+	tunChannelType      = "tun@openssh.com"
+	tunModePointToPoint = 1          // SSH_TUNMODE_POINTOPOINT, layer 3
+	tunUnitAny          = 0x7fffffff // SSH_TUNID_ANY
+
+	// Address families in the packet header use OpenBSD's values on the
+	// wire, whatever the server's platform.
+	tunAFInet  = 2
+	tunAFInet6 = 24
+
+	tunHeaderLen = 8 // uint32 length + uint32 address family
+)
+
+// tunChannelRequest is the extra data of a tun@openssh.com channel open.
+type tunChannelRequest struct {
+	Mode uint32
+	Unit uint32
+}
+
+// openTunChannel asks sshd for a tun device (PermitTunnel) and bridges it
+// to the local adapter. No commands run on the server; its tun interface
+// is configured by the administrator.
+func (t *Tunnel) openTunChannel() error {
+	unit := uint32(tunUnitAny)
+	if t.cfg.TunUnit != "" && t.cfg.TunUnit != "any" {
+		n, err := strconv.ParseUint(t.cfg.TunUnit, 10, 31)
+		if err != nil {
+			return fmt.Errorf("invalid tun_unit %q", t.cfg.TunUnit)
+		}
+		unit = uint32(n)
+	}
+
+	payload := ssh.Marshal(tunChannelRequest{Mode: tunModePointToPoint, Unit: unit})
+	ch, reqs, err := t.client.OpenChannel(tunChannelType, payload)
+	if err != nil {
+		return fmt.Errorf("server refused %s channel (PermitTunnel must be yes or point-to-point): %w", tunChannelType, err)
+	}
+	go ssh.DiscardRequests(reqs)
+
+	t.tunChannel = ch
+	t.startTunChannelBridge(ch)
+	logger.Info("OpenSSH tun channel opened (unit %s)", unitName(unit))
+	return nil
+}
+
+func unitName(unit uint32) string {
+	if unit == tunUnitAny {
+		return "any"
+	}
+	return strconv.FormatUint(uint64(unit), 10)
+}
+
+// startTunChannelBridge moves packets between the local adapter and the
+// channel. Each packet is framed as uint32 length, uint32 address family
+// and the IP packet; the length covers the family and the packet. Batches
+// are coalesced the same way as in startPacketBridge. If the channel ends
+// while the tunnel is up, the tunnel reconnects.
+func (t *Tunnel) startTunChannelBridge(ch ssh.Channel) {
+	stopCh := t.stopCh
+	var failOnce sync.Once
+	fail := func(err error) {
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+		failOnce.Do(func() {
+			logger.Error("SSH tun channel closed, reconnecting: %v", err)
+			t.SetState(protocols.StateReconnecting, "Tun channel closed, reconnecting", nil)
+			go t.Reconnect()
+		})
+	}
+
+	// Read from TUN, write framed packets to SSH
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		batch := t.adapter.BatchSize()
+		bufs := packetBufs(batch)
+		sizes := make([]int, batch)
+		out := make([]byte, 0, 64*1024)
+		var hdr [tunHeaderLen]byte
+		for {
+			select {
+			case <-t.stopCh:
+				return
+			default:
+			}
+
+			n, err := t.adapter.ReadBatch(bufs, sizes, tunpkg.Offset)
+			if err != nil {
+				continue
+			}
+
+			out = out[:0]
+			packets, bytes := 0, 0
+			for i, size := range sizes[:n] {
+				if size == 0 {
+					continue
+				}
+				pkt := bufs[i][tunpkg.Offset : tunpkg.Offset+size]
+
+				var af uint32
+				switch pkt[0] >> 4 {
+				case 4:
+					af = tunAFInet
+				case 6:
+					af = tunAFInet6
+				default:
+					continue
+				}
+				binary.BigEndian.PutUint32(hdr[0:4], uint32(4+size))
+				binary.BigEndian.PutUint32(hdr[4:8], af)
+				out = append(out, hdr[:]...)
+				out = append(out, pkt...)
+				packets++
+				bytes += size
+			}
+			if packets == 0 {
+				continue
+			}
+			if _, err := ch.Write(out); err != nil {
+				fail(err)
+				return
+			}
+			t.countSent(packets, bytes)
+		}
+	}()
+
+	// Read framed packets from SSH, write to TUN
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		r := bufio.NewReaderSize(ch, bridgeReadBuffer)
+		bufs := packetBufs(maxWriteBatch)
+		batch := make([][]byte, 0, maxWriteBatch)
+		hdr := make([]byte, tunHeaderLen)
+		for {
+			select {
+			case <-t.stopCh:
+				return
+			default:
+			}
+
+			// Wait for one packet, then take the ones already buffered
+			batch = batch[:0]
+			bytes := 0
+			for len(batch) < maxWriteBatch && (len(batch) == 0 || r.Buffered() >= tunHeaderLen) {
+				if _, err := io.ReadFull(r, hdr); err != nil {
+					fail(err)
+					return
+				}
+				frameLen := binary.BigEndian.Uint32(hdr[0:4])
+				if frameLen < 4 || frameLen-4 > 65535 {
+					fail(fmt.Errorf("invalid frame length %d", frameLen))
+					return
+				}
+				pktLen := int(frameLen - 4)
+				pkt := bufs[len(batch)][:tunpkg.Offset+pktLen]
+				if _, err := io.ReadFull(r, pkt[tunpkg.Offset:]); err != nil {
+					fail(err)
+					return
+				}
+				if pktLen == 0 {
+					continue
+				}
+				batch = append(batch, pkt)
+				bytes += pktLen
+			}
+
+			if _, err := t.adapter.WriteBatch(batch, tunpkg.Offset); err != nil {
+				continue
+			}
+			t.countReceived(len(batch), bytes)
+		}
+	}()
+}
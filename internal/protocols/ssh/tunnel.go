@@ -4,11 +4,13 @@ package ssh
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
 	"golang.org/x/crypto/ssh"
 
 	"github.com/user/vpn-client/internal/config"
 	"github.com/user/vpn-client/internal/protocols"
+	"github.com/user/vpn-client/internal/proxy"
 	tunpkg "github.com/user/vpn-client/internal/tun"
 )
 
@@ -16,17 +18,37 @@ import (
 type Tunnel struct {
 	*protocols.BaseTunnel
 
-	mu       sync.Mutex
-	cfg      *config.SSH
-	ifaceCfg *config.Interface
-	client   *ssh.Client
-	session  *ssh.Session
-	adapter  *tunpkg.Adapter
-	ctx      context.Context
-	cancel   context.CancelFunc
-	stopCh   chan struct{}
-	stopOnce sync.Once
-	wg       sync.WaitGroup
+	mu         sync.Mutex
+	cfg        *config.SSH
+	ifaceCfg   *config.Interface
+	client     *ssh.Client
+	jumps      []*ssh.Client // jump host connections, first hop first
+	session    *ssh.Session
+	journal    string      // remote journal of the script-mode session
+	tunChannel ssh.Channel // tun@openssh.com channel in native mode
+	adapter    *tunpkg.Adapter
+	ctx        context.Context
+	cancel     context.CancelFunc
+	stopCh     chan struct{}
+	stopOnce   sync.Once
+	wg         sync.WaitGroup
+	prompt     protocols.PromptFunc // asks about unknown host keys
+
+	// Proxy mode (ssh-proxy): local proxies instead of a TUN adapter
+	proxyCfg *config.Proxy
+	proxy    *proxy.Server
+
+	// Transfer counters of the packet bridge or the proxied connections,
+	// updated while Stats reads them
+	bytesSent     atomic.Uint64
+	bytesReceived atomic.Uint64
+	packetsSent   atomic.Uint64
+	packetsRecv   atomic.Uint64
+
+	// Port forwards (ssh.forwards), guarded by fwdMu so status reads do
+	// not wait for Start
+	fwdMu    sync.Mutex
+	forwards []*portForward
 }
 
 // New creates a new SSH tunnel.
@@ -39,6 +61,22 @@ func New(cfg *config.SSH, ifaceCfg *config.Interface) *Tunnel {
 	}
 }
 
+// NewProxy creates an SSH tunnel in proxy mode: local SOCKS5/HTTP proxies
+// open direct-tcpip channels through the server, like ssh -D. It needs no
+// TUN adapter and no elevated privileges.
+func NewProxy(cfg *config.SSH, ifaceCfg *config.Interface, proxyCfg *config.Proxy) *Tunnel {
+	t := New(cfg, ifaceCfg)
+	t.proxyCfg = proxyCfg
+	return t
+}
+
+// SetPromptFunc sets the function asking whether to trust unknown servers.
+func (t *Tunnel) SetPromptFunc(fn protocols.PromptFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prompt = fn
+}
+
 // GetAdapter returns the TUN adapter.
 func (t *Tunnel) GetAdapter() *tunpkg.Adapter {
 	t.mu.Lock()
@@ -53,6 +91,17 @@ func (t *Tunnel) GetClient() *ssh.Client {
 	return t.client
 }
 
+// Stats returns the transfer counters: packet bytes in TUN mode, proxied
+// bytes in proxy mode.
+func (t *Tunnel) Stats() protocols.Stats {
+	return protocols.Stats{
+		BytesSent:     t.bytesSent.Load(),
+		BytesReceived: t.bytesReceived.Load(),
+		PacketsSent:   t.packetsSent.Load(),
+		PacketsRecv:   t.packetsRecv.Load(),
+	}
+}
+
 // Helper functions
 func containsSlash(s string) bool {
 	for _, c := range s {
@@ -73,15 +122,29 @@ func indexOf(s string, c rune) int {
 }
 
 func (t *Tunnel) cleanup() {
+	t.stopForwards()
+
+	if t.proxy != nil {
+		t.proxy.Stop()
+		t.proxy = nil
+	}
+
 	if t.session != nil {
 		t.session.Close()
 		t.session = nil
 	}
+	t.journal = "" // reverted by Stop or on the next connect
+
+	if t.tunChannel != nil {
+		t.tunChannel.Close()
+		t.tunChannel = nil
+	}
 
 	if t.client != nil {
 		t.client.Close()
 		t.client = nil
 	}
+	t.closeJumps()
 
 	if t.adapter != nil {
 		t.adapter.Down()
@@ -0,0 +1,65 @@
+package wireguard
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// KeyLen is the length of WireGuard keys in bytes.
+const KeyLen = 32
+
+// GeneratePrivateKey returns a new base64-encoded Curve25519 private key.
+func GeneratePrivateKey() (string, error) {
+	key, err := randomKey()
+	if err != nil {
+		return "", err
+	}
+	// Clamp as described in RFC 7748, like `wg genkey`.
+	key[0] &= 248
+	key[31] = (key[31] & 127) | 64
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// GeneratePresharedKey returns a new base64-encoded preshared key.
+func GeneratePresharedKey() (string, error) {
+	key, err := randomKey()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// PublicKey derives the base64-encoded public key from a private key.
+func PublicKey(privateKey string) (string, error) {
+	priv, err := decodeKey(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid private key: %w", err)
+	}
+	pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive public key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(pub), nil
+}
+
+func randomKey() ([]byte, error) {
+	key := make([]byte, KeyLen)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key, nil
+}
+
+func decodeKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != KeyLen {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeyLen, len(key))
+	}
+	return key, nil
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package wireguard
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// markControl returns a net.Dialer Control function that sets SO_MARK.
+func markControl(mark uint32) func(network, address string, c syscall.RawConn) error {
+	if mark == 0 {
+		return nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		var opErr error
+		err := c.Control(func(fd uintptr) {
+			opErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, int(mark))
+		})
+		if err != nil {
+			return err
+		}
+		return opErr
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package wireguard
+
+import "syscall"
+
+// markControl returns nil: socket marks only exist on Linux.
+func markControl(mark uint32) func(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,192 @@
+package wireguard
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math/big"
+
+	"golang.zx2c4.com/wireguard/conn"
+	"golang.zx2c4.com/wireguard/device"
+
+	"github.com/user/vpn-client/internal/config"
+)
+
+// obfuscationParams holds AmneziaWG-compatible obfuscation settings with
+// zero header values replaced by the standard WireGuard message types.
+type obfuscationParams struct {
+	jc, jmin, jmax int
+	s1, s2         int
+	h1, h2, h3, h4 uint32
+}
+
+func newObfuscationParams(cfg *config.WireGuard) obfuscationParams {
+	p := obfuscationParams{
+		jc:   cfg.Jc,
+		jmin: cfg.Jmin,
+		jmax: cfg.Jmax,
+		s1:   cfg.S1,
+		s2:   cfg.S2,
+		h1:   cfg.H1,
+		h2:   cfg.H2,
+		h3:   cfg.H3,
+		h4:   cfg.H4,
+	}
+	if p.h1 == 0 {
+		p.h1 = device.MessageInitiationType
+	}
+	if p.h2 == 0 {
+		p.h2 = device.MessageResponseType
+	}
+	if p.h3 == 0 {
+		p.h3 = device.MessageCookieReplyType
+	}
+	if p.h4 == 0 {
+		p.h4 = device.MessageTransportType
+	}
+	return p
+}
+
+// obfuscatedBind wraps a conn.Bind and rewrites WireGuard messages on the
+// wire the way AmneziaWG does: junk packets before each handshake
+// initiation, random prefixes on handshake messages and custom message
+// type headers. wireguard-go itself only ever sees standard messages.
+type obfuscatedBind struct {
+	conn.Bind
+	p obfuscationParams
+}
+
+// newObfuscatedBind wraps inner with AmneziaWG obfuscation.
+func newObfuscatedBind(inner conn.Bind, cfg *config.WireGuard) conn.Bind {
+	return &obfuscatedBind{Bind: inner, p: newObfuscationParams(cfg)}
+}
+
+// Open opens the inner bind and wraps its receive functions.
+func (b *obfuscatedBind) Open(port uint16) ([]conn.ReceiveFunc, uint16, error) {
+	fns, actualPort, err := b.Bind.Open(port)
+	if err != nil {
+		return nil, 0, err
+	}
+	wrapped := make([]conn.ReceiveFunc, len(fns))
+	for i, fn := range fns {
+		wrapped[i] = b.wrapReceive(fn)
+	}
+	return wrapped, actualPort, nil
+}
+
+// Send obfuscates outgoing messages before handing them to the inner bind.
+// Handshake messages are copied; transport headers are rewritten in the
+// caller's buffers and restored once the inner bind has sent them, so the
+// buffers are unchanged when Send returns.
+func (b *obfuscatedBind) Send(bufs [][]byte, ep conn.Endpoint) error {
+	out := bufs
+	copied := false
+	var transport [][]byte
+	// replace stores a copy of bufs[i] with a random prefix and a custom
+	// header, leaving the caller's buffers untouched.
+	replace := func(i, pad int, h uint32) {
+		if !copied {
+			out = append([][]byte(nil), bufs...)
+			copied = true
+		}
+		pkt := make([]byte, pad+len(bufs[i]))
+		rand.Read(pkt[:pad])
+		copy(pkt[pad:], bufs[i])
+		binary.LittleEndian.PutUint32(pkt[pad:pad+4], h)
+		out[i] = pkt
+	}
+
+	for i, buf := range bufs {
+		if len(buf) < 4 {
+			continue
+		}
+		switch binary.LittleEndian.Uint32(buf[:4]) {
+		case device.MessageInitiationType:
+			if err := b.sendJunk(ep); err != nil {
+				return err
+			}
+			replace(i, b.p.s1, b.p.h1)
+		case device.MessageResponseType:
+			replace(i, b.p.s2, b.p.h2)
+		case device.MessageCookieReplyType:
+			replace(i, 0, b.p.h3)
+		case device.MessageTransportType:
+			// Copying every data packet would be costly on the hot path
+			binary.LittleEndian.PutUint32(buf[:4], b.p.h4)
+			transport = append(transport, buf)
+		}
+	}
+	err := b.Bind.Send(out, ep)
+	for _, buf := range transport {
+		binary.LittleEndian.PutUint32(buf[:4], device.MessageTransportType)
+	}
+	return err
+}
+
+// sendJunk sends Jc random packets of Jmin..Jmax bytes.
+func (b *obfuscatedBind) sendJunk(ep conn.Endpoint) error {
+	if b.p.jc <= 0 {
+		return nil
+	}
+	junk := make([][]byte, 0, b.p.jc)
+	for i := 0; i < b.p.jc; i++ {
+		size := b.p.jmin
+		if b.p.jmax > b.p.jmin {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(b.p.jmax-b.p.jmin+1)))
+			if err == nil {
+				size += int(n.Int64())
+			}
+		}
+		pkt := make([]byte, size)
+		rand.Read(pkt)
+		junk = append(junk, pkt)
+	}
+	// Respect the inner bind's batch limit.
+	batch := b.Bind.BatchSize()
+	for len(junk) > 0 {
+		n := min(batch, len(junk))
+		if err := b.Bind.Send(junk[:n], ep); err != nil {
+			return err
+		}
+		junk = junk[n:]
+	}
+	return nil
+}
+
+// wrapReceive restores standard WireGuard messages from obfuscated ones.
+// Packets that do not match any expected shape (e.g. junk) are dropped by
+// setting their size to zero.
+func (b *obfuscatedBind) wrapReceive(fn conn.ReceiveFunc) conn.ReceiveFunc {
+	return func(packets [][]byte, sizes []int, eps []conn.Endpoint) (int, error) {
+		n, err := fn(packets, sizes, eps)
+		for i := 0; i < n; i++ {
+			sizes[i] = b.deobfuscate(packets[i], sizes[i])
+		}
+		return n, err
+	}
+}
+
+// deobfuscate rewrites pkt[:size] in place and returns the new size.
+func (b *obfuscatedBind) deobfuscate(pkt []byte, size int) int {
+	header := func(off int) uint32 {
+		return binary.LittleEndian.Uint32(pkt[off : off+4])
+	}
+	restore := func(off int, typ uint32) int {
+		if off > 0 {
+			copy(pkt, pkt[off:size])
+		}
+		binary.LittleEndian.PutUint32(pkt[:4], typ)
+		return size - off
+	}
+
+	switch {
+	case size == b.p.s1+device.MessageInitiationSize && header(b.p.s1) == b.p.h1:
+		return restore(b.p.s1, device.MessageInitiationType)
+	case size == b.p.s2+device.MessageResponseSize && header(b.p.s2) == b.p.h2:
+		return restore(b.p.s2, device.MessageResponseType)
+	case size == device.MessageCookieReplySize && header(0) == b.p.h3:
+		return restore(0, device.MessageCookieReplyType)
+	case size >= device.MessageTransportSize && header(0) == b.p.h4:
+		return restore(0, device.MessageTransportType)
+	}
+	return 0
+}
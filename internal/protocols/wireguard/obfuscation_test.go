@@ -0,0 +1,230 @@
+package wireguard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.zx2c4.com/wireguard/conn"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/tun/tuntest"
+
+	"github.com/user/vpn-client/internal/config"
+)
+
+// testObfuscation sets every AmneziaWG parameter.
+var testObfuscation = &config.WireGuard{
+	Jc: 4, Jmin: 40, Jmax: 70,
+	S1: 15, S2: 25,
+	H1: 1020325451, H2: 3288052141, H3: 1766607858, H4: 2528465083,
+}
+
+// testPeer is a WireGuard device on a channel TUN.
+type testPeer struct {
+	dev *device.Device
+	tun *tuntest.ChannelTUN
+	pub string // hex, as used by the UAPI
+	ip  netip.Addr
+}
+
+func newTestPeer(t *testing.T, bind conn.Bind, ip string) *testPeer {
+	t.Helper()
+	priv, err := GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := PublicKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &testPeer{
+		tun: tuntest.NewChannelTUN(),
+		pub: hexKey(t, pub),
+		ip:  netip.MustParseAddr(ip),
+	}
+	p.dev = device.NewDevice(p.tun.TUN(), bind, device.NewLogger(device.LogLevelError, ip+": "))
+	t.Cleanup(p.dev.Close)
+
+	if err := p.dev.IpcSet("private_key=" + hexKey(t, priv) + "\nlisten_port=0\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.dev.Up(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func hexKey(t *testing.T, b64 string) string {
+	t.Helper()
+	key, err := decodeKey(b64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(key)
+}
+
+// port returns the UDP port the device listens on.
+func (p *testPeer) port(t *testing.T) int {
+	t.Helper()
+	cfg, err := p.dev.IpcGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(cfg, "\n") {
+		if v, ok := strings.CutPrefix(line, "listen_port="); ok {
+			port, _ := strconv.Atoi(v)
+			return port
+		}
+	}
+	t.Fatal("device has no listen_port")
+	return 0
+}
+
+// connectPeers makes a send to b at endpoint; b learns a's endpoint from
+// the handshake.
+func connectPeers(t *testing.T, a, b *testPeer, endpoint string) {
+	t.Helper()
+	if err := a.dev.IpcSet(fmt.Sprintf("public_key=%s\nendpoint=%s\nallowed_ip=%s/32\n", b.pub, endpoint, b.ip)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.dev.IpcSet(fmt.Sprintf("public_key=%s\nallowed_ip=%s/32\n", a.pub, a.ip)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ping sends a packet from a to b through the tunnel, which first
+// completes a handshake, and checks that it arrives unchanged.
+func ping(t *testing.T, a, b *testPeer) {
+	t.Helper()
+	msg := tuntest.Ping(b.ip, a.ip)
+	a.tun.Outbound <- msg
+	select {
+	case got := <-b.tun.Inbound:
+		if !bytes.Equal(got, msg) {
+			t.Fatalf("packet changed in transit:\n got %x\nwant %x", got, msg)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("packet did not arrive")
+	}
+}
+
+// deliveredBind counts the packets its bind hands to the device.
+type deliveredBind struct {
+	conn.Bind
+	delivered atomic.Int64
+}
+
+func (b *deliveredBind) Open(port uint16) ([]conn.ReceiveFunc, uint16, error) {
+	fns, actual, err := b.Bind.Open(port)
+	for i, fn := range fns {
+		fns[i] = func(packets [][]byte, sizes []int, eps []conn.Endpoint) (int, error) {
+			n, err := fn(packets, sizes, eps)
+			for _, size := range sizes[:n] {
+				if size > 0 {
+					b.delivered.Add(1)
+				}
+			}
+			return n, err
+		}
+	}
+	return fns, actual, err
+}
+
+func TestObfuscatedBindLoopback(t *testing.T) {
+	a := newTestPeer(t, newObfuscatedBind(conn.NewStdNetBind(), testObfuscation), "10.9.0.1")
+	counter := &deliveredBind{Bind: newObfuscatedBind(conn.NewStdNetBind(), testObfuscation)}
+	b := newTestPeer(t, counter, "10.9.0.2")
+	endpoint := fmt.Sprintf("127.0.0.1:%d", b.port(t))
+
+	// Packets not matching the obfuscation never reach the device: junk,
+	// an initiation without prefix and standard WireGuard messages
+	raw, err := net.Dial("udp", endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	for _, pkt := range [][]byte{
+		bytes.Repeat([]byte{0xa5}, 60),
+		header(device.MessageInitiationType, device.MessageInitiationSize),
+		header(testObfuscation.H1, device.MessageInitiationSize),
+		header(device.MessageTransportType, device.MessageTransportSize+16),
+	} {
+		if _, err := raw.Write(pkt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := counter.delivered.Load(); n != 0 {
+		t.Fatalf("%d non-matching packets reached the device", n)
+	}
+
+	connectPeers(t, a, b, endpoint)
+	ping(t, a, b)
+	ping(t, b, a)
+	if counter.delivered.Load() == 0 {
+		t.Fatal("no packets reached the device")
+	}
+}
+
+// header returns a packet of size bytes starting with message type typ.
+func header(typ uint32, size int) []byte {
+	pkt := make([]byte, size)
+	binary.LittleEndian.PutUint32(pkt, typ)
+	return pkt
+}
+
+// recordingBind keeps the packets sent through it.
+type recordingBind struct {
+	conn.Bind
+	sent [][]byte
+}
+
+func (b *recordingBind) Send(bufs [][]byte, ep conn.Endpoint) error {
+	for _, buf := range bufs {
+		b.sent = append(b.sent, append([]byte(nil), buf...))
+	}
+	return nil
+}
+
+func (b *recordingBind) BatchSize() int { return 1 }
+
+func TestObfuscatedBindSendKeepsBuffers(t *testing.T) {
+	inner := &recordingBind{}
+	bind := newObfuscatedBind(inner, testObfuscation)
+
+	initiation := header(device.MessageInitiationType, device.MessageInitiationSize)
+	transport := header(device.MessageTransportType, device.MessageTransportSize+16)
+	bufs := [][]byte{initiation, transport}
+	orig := [][]byte{bytes.Clone(initiation), bytes.Clone(transport)}
+
+	if err := bind.Send(bufs, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := range bufs {
+		if !bytes.Equal(bufs[i], orig[i]) {
+			t.Errorf("Send modified buffer %d", i)
+		}
+	}
+
+	// Junk, then the two obfuscated messages
+	if len(inner.sent) != testObfuscation.Jc+2 {
+		t.Fatalf("sent %d packets, want %d", len(inner.sent), testObfuscation.Jc+2)
+	}
+	init, data := inner.sent[testObfuscation.Jc], inner.sent[testObfuscation.Jc+1]
+	if len(init) != testObfuscation.S1+device.MessageInitiationSize ||
+		binary.LittleEndian.Uint32(init[testObfuscation.S1:]) != testObfuscation.H1 {
+		t.Errorf("initiation not obfuscated: %x", init)
+	}
+	if binary.LittleEndian.Uint32(data) != testObfuscation.H4 {
+		t.Errorf("transport header not rewritten: %x", data[:4])
+	}
+}
@@ -0,0 +1,197 @@
+package wireguard
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.zx2c4.com/wireguard/device"
+
+	"github.com/user/vpn-client/internal/protocols"
+)
+
+// uapiTimeout bounds a single UAPI request to a running device.
+const uapiTimeout = 5 * time.Second
+
+// DeviceStatus is the runtime state of a WireGuard device.
+type DeviceStatus struct {
+	PublicKey  string
+	ListenPort int
+	Peers      []PeerStatus
+}
+
+// PeerStatus is the runtime state of a single peer.
+type PeerStatus struct {
+	PublicKey           string
+	Endpoint            string
+	AllowedIPs          []string
+	LastHandshake       time.Time // zero if no handshake yet
+	RxBytes             uint64
+	TxBytes             uint64
+	PersistentKeepalive int
+}
+
+// QueryDevice reads the state of the running device iface over its UAPI
+// socket.
+func QueryDevice(iface string) (*DeviceStatus, error) {
+	c, err := dialUAPI(iface)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s is not running: %w", iface, err)
+	}
+	defer c.Close()
+
+	c.SetDeadline(time.Now().Add(uapiTimeout))
+	if _, err := io.WriteString(c, "get=1\n\n"); err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", iface, err)
+	}
+	return parseIpcGet(c)
+}
+
+// FindDevice locates the running device with the public key pub. iface is
+// tried first; the kernel may have named the adapter differently (utunN on
+// macOS), so every other UAPI socket is checked next. It returns the
+// actual interface name with the device state.
+func FindDevice(iface, pub string) (string, *DeviceStatus, error) {
+	status, err := QueryDevice(iface)
+	if err == nil && (pub == "" || status.PublicKey == pub) {
+		return iface, status, nil
+	}
+	if pub != "" {
+		for _, name := range listUAPI() {
+			if name == iface {
+				continue
+			}
+			if s, err := QueryDevice(name); err == nil && s.PublicKey == pub {
+				return name, s, nil
+			}
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("interface %s runs a different key", iface)
+	}
+	return "", nil, err
+}
+
+// parseIpcGet parses the response of a UAPI get operation.
+func parseIpcGet(r io.Reader) (*DeviceStatus, error) {
+	status := &DeviceStatus{}
+	var peer *PeerStatus
+	var hsSec, hsNsec int64
+
+	flushPeer := func() {
+		if peer == nil {
+			return
+		}
+		if hsSec != 0 || hsNsec != 0 {
+			peer.LastHandshake = time.Unix(hsSec, hsNsec)
+		}
+		status.Peers = append(status.Peers, *peer)
+		peer, hsSec, hsNsec = nil, 0, 0
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed UAPI line: %q", line)
+		}
+
+		switch key {
+		case "errno":
+			if value != "0" {
+				return nil, fmt.Errorf("UAPI error: errno=%s", value)
+			}
+		case "private_key":
+			priv, err := hexToBase64(value)
+			if err != nil {
+				return nil, err
+			}
+			if status.PublicKey, err = PublicKey(priv); err != nil {
+				return nil, err
+			}
+		case "listen_port":
+			status.ListenPort, _ = strconv.Atoi(value)
+		case "public_key":
+			flushPeer()
+			pub, err := hexToBase64(value)
+			if err != nil {
+				return nil, err
+			}
+			peer = &PeerStatus{PublicKey: pub}
+		}
+
+		if peer == nil {
+			continue
+		}
+		switch key {
+		case "endpoint":
+			peer.Endpoint = value
+		case "allowed_ip":
+			peer.AllowedIPs = append(peer.AllowedIPs, value)
+		case "last_handshake_time_sec":
+			hsSec, _ = strconv.ParseInt(value, 10, 64)
+		case "last_handshake_time_nsec":
+			hsNsec, _ = strconv.ParseInt(value, 10, 64)
+		case "rx_bytes":
+			peer.RxBytes, _ = strconv.ParseUint(value, 10, 64)
+		case "tx_bytes":
+			peer.TxBytes, _ = strconv.ParseUint(value, 10, 64)
+		case "persistent_keepalive_interval":
+			peer.PersistentKeepalive, _ = strconv.Atoi(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read UAPI response: %w", err)
+	}
+	flushPeer()
+
+	return status, nil
+}
+
+// deviceStats sums the transfer counters of all peers of a running device.
+func deviceStats(dev *device.Device) protocols.Stats {
+	var stats protocols.Stats
+	if dev == nil {
+		return stats
+	}
+	uapi, err := dev.IpcGet()
+	if err != nil {
+		return stats
+	}
+	status, err := parseIpcGet(strings.NewReader(uapi))
+	if err != nil {
+		return stats
+	}
+	for _, p := range status.Peers {
+		stats.BytesReceived += p.RxBytes
+		stats.BytesSent += p.TxBytes
+	}
+	return stats
+}
+
+// Stats returns the transfer counters of the device.
+func (t *Tunnel) Stats() protocols.Stats {
+	return deviceStats(t.GetDevice())
+}
+
+// Stats returns the transfer counters of the device.
+func (t *UserspaceTunnel) Stats() protocols.Stats {
+	return deviceStats(t.GetDevice())
+}
+
+func hexToBase64(s string) (string, error) {
+	key, err := hex.DecodeString(s)
+	if err != nil || len(key) != KeyLen {
+		return "", fmt.Errorf("invalid key in UAPI response")
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
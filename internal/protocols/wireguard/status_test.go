@@ -0,0 +1,45 @@
+//go:build !windows
+
+package wireguard
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"golang.zx2c4.com/wireguard/conn"
+)
+
+func TestFindDeviceByKey(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("UAPI sockets need root")
+	}
+	p := newTestPeer(t, conn.NewStdNetBind(), "10.9.2.1")
+	cfg, err := p.dev.IpcGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := parseIpcGet(strings.NewReader(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The socket is named after the adapter, not the configured interface
+	uapi, err := startUAPI(p.dev, "vpntest-utun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uapi.Close()
+
+	name, status, err := FindDevice("vpntest-cfg", want.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "vpntest-utun" || status.PublicKey != want.PublicKey {
+		t.Fatalf("found %s with key %s", name, status.PublicKey)
+	}
+
+	if _, _, err := FindDevice("vpntest-cfg", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="); err == nil {
+		t.Fatal("found a device for an unknown key")
+	}
+}
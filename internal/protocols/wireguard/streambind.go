@@ -0,0 +1,277 @@
+package wireguard
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+	"golang.zx2c4.com/wireguard/conn"
+
+	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/relay"
+)
+
+const (
+	streamDialTimeout   = 10 * time.Second
+	streamRedialBackoff = 2 * time.Second
+)
+
+// streamBind is a conn.Bind that carries WireGuard datagrams over a single
+// TCP or WebSocket stream to a relay (see package relay). The stream is
+// dialed lazily and re-dialed after failures; WireGuard's own handshake
+// retries drive recovery.
+type streamBind struct {
+	transport string // config.WireGuardTransportTCP or ...WebSocket
+	relay     string // host:port or ws(s):// URL
+
+	dialMu  sync.Mutex // serializes dials without holding mu
+	mu      sync.Mutex
+	stream  net.Conn
+	ep      *streamEndpoint
+	closeCh chan struct{}
+	open    bool
+	mark    uint32
+}
+
+// newStreamBind creates a stream bind for cfg.Transport and cfg.Relay.
+func newStreamBind(cfg *config.WireGuard) *streamBind {
+	return &streamBind{
+		transport: cfg.Transport,
+		relay:     cfg.Relay,
+	}
+}
+
+// streamEndpoint is the only endpoint a stream bind talks to: the relay.
+type streamEndpoint struct {
+	addr netip.AddrPort
+}
+
+func (e *streamEndpoint) ClearSrc()           {}
+func (e *streamEndpoint) SrcToString() string { return "" }
+func (e *streamEndpoint) DstToString() string { return e.addr.String() }
+func (e *streamEndpoint) DstToBytes() []byte  { b, _ := e.addr.MarshalBinary(); return b }
+func (e *streamEndpoint) DstIP() netip.Addr   { return e.addr.Addr() }
+func (e *streamEndpoint) SrcIP() netip.Addr   { return netip.Addr{} }
+
+// Open marks the bind usable and returns its single receive function.
+func (b *streamBind) Open(port uint16) ([]conn.ReceiveFunc, uint16, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.open {
+		return nil, 0, conn.ErrBindAlreadyOpen
+	}
+	b.open = true
+	b.closeCh = make(chan struct{})
+
+	return []conn.ReceiveFunc{b.makeReceive(b.closeCh)}, port, nil
+}
+
+// Close drops the stream and unblocks the receive function.
+func (b *streamBind) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	b.open = false
+	close(b.closeCh)
+	if b.stream != nil {
+		b.stream.Close()
+		b.stream = nil
+	}
+	return nil
+}
+
+// SetMark sets the fwmark of future relay streams and re-dials the current
+// one so that it is excluded from policy routing too.
+func (b *streamBind) SetMark(mark uint32) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.mark == mark {
+		return nil
+	}
+	b.mark = mark
+	if b.stream != nil {
+		b.stream.Close()
+		b.stream = nil
+	}
+	return nil
+}
+
+// BatchSize returns 1: frames are written one at a time.
+func (b *streamBind) BatchSize() int {
+	return 1
+}
+
+// ParseEndpoint accepts any ip:port; the relay decides the real target.
+func (b *streamBind) ParseEndpoint(s string) (conn.Endpoint, error) {
+	addr, err := netip.ParseAddrPort(s)
+	if err != nil {
+		return nil, err
+	}
+	ep := &streamEndpoint{addr: addr}
+	b.mu.Lock()
+	b.ep = ep
+	b.mu.Unlock()
+	return ep, nil
+}
+
+// endpoint returns the configured peer endpoint, which every received
+// packet is attributed to so WireGuard roaming never changes it.
+func (b *streamBind) endpoint() conn.Endpoint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ep == nil {
+		return &streamEndpoint{}
+	}
+	return b.ep
+}
+
+// Send writes each buffer as one frame on the stream.
+func (b *streamBind) Send(bufs [][]byte, ep conn.Endpoint) error {
+	stream, err := b.getStream()
+	if err != nil {
+		return err
+	}
+	for _, buf := range bufs {
+		if err := relay.WriteFrame(stream, buf); err != nil {
+			b.dropStream(stream)
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *streamBind) makeReceive(closeCh chan struct{}) conn.ReceiveFunc {
+	return func(packets [][]byte, sizes []int, eps []conn.Endpoint) (int, error) {
+		for {
+			select {
+			case <-closeCh:
+				return 0, net.ErrClosed
+			default:
+			}
+
+			stream, err := b.getStream()
+			if err != nil {
+				logger.Debug("WireGuard relay unavailable: %v", err)
+				select {
+				case <-closeCh:
+					return 0, net.ErrClosed
+				case <-time.After(streamRedialBackoff):
+				}
+				continue
+			}
+
+			n, err := relay.ReadFrame(stream, packets[0])
+			if err != nil {
+				b.dropStream(stream)
+				continue
+			}
+
+			sizes[0] = n
+			eps[0] = b.endpoint()
+			return 1, nil
+		}
+	}
+}
+
+// getStream returns the current stream, dialing the relay if needed.
+func (b *streamBind) getStream() (net.Conn, error) {
+	if stream, err := b.current(); stream != nil || err != nil {
+		return stream, err
+	}
+
+	b.dialMu.Lock()
+	defer b.dialMu.Unlock()
+
+	// Another caller may have dialed while we waited.
+	if stream, err := b.current(); stream != nil || err != nil {
+		return stream, err
+	}
+
+	stream, err := b.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		stream.Close()
+		return nil, net.ErrClosed
+	}
+	logger.Info("WireGuard relay stream connected to %s", b.relay)
+	b.stream = stream
+	return stream, nil
+}
+
+// current returns the open stream, or net.ErrClosed if the bind is closed.
+func (b *streamBind) current() (net.Conn, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return nil, net.ErrClosed
+	}
+	return b.stream, nil
+}
+
+// dropStream closes stream if it is still the current one.
+func (b *streamBind) dropStream(stream net.Conn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stream == stream {
+		logger.Warning("WireGuard relay stream to %s lost", b.relay)
+		b.stream.Close()
+		b.stream = nil
+	}
+}
+
+func (b *streamBind) dial() (net.Conn, error) {
+	b.mu.Lock()
+	dialer := &net.Dialer{Timeout: streamDialTimeout, Control: markControl(b.mark)}
+	b.mu.Unlock()
+
+	switch b.transport {
+	case config.WireGuardTransportTCP:
+		c, err := dialer.Dial("tcp", b.relay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to relay: %w", err)
+		}
+		if tc, ok := c.(*net.TCPConn); ok {
+			tc.SetNoDelay(true)
+		}
+		return c, nil
+
+	case config.WireGuardTransportWebSocket:
+		u, err := url.Parse(b.relay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid relay URL: %w", err)
+		}
+		origin := "http://" + u.Host
+		wsCfg, err := websocket.NewConfig(b.relay, origin)
+		if err != nil {
+			return nil, fmt.Errorf("invalid relay URL: %w", err)
+		}
+		wsCfg.Dialer = dialer
+		if u.Scheme == "wss" {
+			wsCfg.TlsConfig = &tls.Config{ServerName: u.Hostname()}
+		}
+		ws, err := websocket.DialConfig(wsCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to relay: %w", err)
+		}
+		ws.PayloadType = websocket.BinaryFrame
+		return ws, nil
+	}
+	return nil, fmt.Errorf("unsupported transport: %s", b.transport)
+}
@@ -0,0 +1,65 @@
+package wireguard
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"testing"
+
+	"golang.zx2c4.com/wireguard/conn"
+
+	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/relay"
+)
+
+// startRelay runs a relay server on 127.0.0.1 forwarding to target and
+// returns its address.
+func startRelay(t *testing.T, target, wsPath string) net.Addr {
+	t.Helper()
+	started := make(chan net.Addr, 1)
+	srv := relay.NewServer(&relay.ServerConfig{
+		Listen:    "127.0.0.1:0",
+		Target:    target,
+		WSPath:    wsPath,
+		Logger:    log.New(io.Discard, "", 0),
+		OnStarted: func(addr net.Addr) { started <- addr },
+	})
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+	t.Cleanup(func() { srv.Close() })
+
+	select {
+	case addr := <-started:
+		return addr
+	case err := <-errCh:
+		t.Fatalf("relay failed: %v", err)
+		return nil
+	}
+}
+
+func TestStreamBindRelay(t *testing.T) {
+	tests := []struct {
+		transport string
+		wsPath    string
+		relayURL  func(addr net.Addr) string
+	}{
+		{config.WireGuardTransportTCP, "", func(addr net.Addr) string { return addr.String() }},
+		{config.WireGuardTransportWebSocket, "/wg", func(addr net.Addr) string { return "ws://" + addr.String() + "/wg" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.transport, func(t *testing.T) {
+			server := newTestPeer(t, conn.NewStdNetBind(), "10.9.1.1")
+			addr := startRelay(t, fmt.Sprintf("127.0.0.1:%d", server.port(t)), tt.wsPath)
+
+			client := newTestPeer(t, newStreamBind(&config.WireGuard{
+				Transport: tt.transport,
+				Relay:     tt.relayURL(addr),
+			}), "10.9.1.2")
+
+			connectPeers(t, client, server, addr.String())
+			ping(t, client, server)
+			ping(t, server, client)
+		})
+	}
+}
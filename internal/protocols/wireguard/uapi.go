@@ -0,0 +1,48 @@
+package wireguard
+
+import (
+	"net"
+
+	"golang.zx2c4.com/wireguard/device"
+
+	"github.com/user/vpn-client/internal/logger"
+)
+
+// uapiServer exposes a device on the standard UAPI socket so that wg(8)
+// and monitoring agents can inspect it.
+type uapiServer struct {
+	iface string
+	ln    net.Listener
+	done  chan struct{}
+}
+
+// startUAPI starts serving dev on the UAPI socket of iface.
+func startUAPI(dev *device.Device, iface string) (*uapiServer, error) {
+	ln, err := listenUAPI(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &uapiServer{iface: iface, ln: ln, done: make(chan struct{})}
+	go func() {
+		defer logger.Recover("wireguard-uapi")
+		defer close(s.done)
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go dev.IpcHandle(c)
+		}
+	}()
+
+	logger.Debug("WireGuard UAPI listening for %s", iface)
+	return s, nil
+}
+
+// Close stops accepting UAPI connections and removes the socket.
+func (s *uapiServer) Close() {
+	s.ln.Close()
+	<-s.done
+	removeUAPI(s.iface)
+}
@@ -0,0 +1,55 @@
+//go:build !windows
+
+package wireguard
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.zx2c4.com/wireguard/ipc"
+)
+
+// uapiSocketDir is where wireguard-go and wg(8) keep UAPI sockets.
+const uapiSocketDir = "/var/run/wireguard"
+
+// uapiSocketPath returns the UAPI socket path of iface.
+func uapiSocketPath(iface string) string {
+	return filepath.Join(uapiSocketDir, iface+".sock")
+}
+
+func dialUAPI(iface string) (net.Conn, error) {
+	return net.DialTimeout("unix", uapiSocketPath(iface), uapiTimeout)
+}
+
+// listenUAPI creates /var/run/wireguard/<iface>.sock. ipc.UAPIOpen creates
+// the socket with umask 077, so only root can talk to the device.
+func listenUAPI(iface string) (net.Listener, error) {
+	file, err := ipc.UAPIOpen(iface)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ipc.UAPIListen(iface, file)
+}
+
+// listUAPI returns the interfaces that have a UAPI socket.
+func listUAPI() []string {
+	entries, err := os.ReadDir(uapiSocketDir)
+	if err != nil {
+		return nil
+	}
+	var ifaces []string
+	for _, e := range entries {
+		if iface, ok := strings.CutSuffix(e.Name(), ".sock"); ok {
+			ifaces = append(ifaces, iface)
+		}
+	}
+	return ifaces
+}
+
+// removeUAPI deletes the socket file left behind by a closed listener.
+func removeUAPI(iface string) {
+	os.Remove(uapiSocketPath(iface))
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package wireguard
+
+import (
+	"net"
+
+	"golang.zx2c4.com/wireguard/ipc"
+	"golang.zx2c4.com/wireguard/ipc/namedpipe"
+)
+
+// uapiPipePath returns the UAPI named pipe path of iface, as used by
+// wireguard-go and wg.exe.
+func uapiPipePath(iface string) string {
+	return `\\.\pipe\ProtectedPrefix\Administrators\WireGuard\` + iface
+}
+
+func dialUAPI(iface string) (net.Conn, error) {
+	return namedpipe.DialTimeout(uapiPipePath(iface), uapiTimeout)
+}
+
+// listenUAPI creates the UAPI named pipe of iface. ipc.UAPIListen restricts
+// it to SYSTEM and Administrators.
+func listenUAPI(iface string) (net.Listener, error) {
+	return ipc.UAPIListen(iface)
+}
+
+// listUAPI returns nil: Wintun adapters keep their configured name, so
+// the pipe of the configured interface is always the right one.
+func listUAPI() []string { return nil }
+
+// removeUAPI is a no-op: named pipes disappear with their listener.
+func removeUAPI(iface string) {}
@@ -0,0 +1,206 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/tun/netstack"
+
+	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/pmtu"
+	"github.com/user/vpn-client/internal/protocols"
+	"github.com/user/vpn-client/internal/proxy"
+)
+
+// UserspaceTunnel runs WireGuard on a userspace network stack and exposes
+// local SOCKS5/HTTP proxies that dial through it. It needs no TUN adapter
+// and no elevated privileges.
+type UserspaceTunnel struct {
+	*protocols.BaseTunnel
+
+	mu       sync.Mutex
+	cfg      *config.WireGuard
+	ifaceCfg *config.Interface
+	proxyCfg *config.Proxy
+	tnet     *netstack.Net
+	device   *device.Device
+	proxy    *proxy.Server
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewUserspace creates a new userspace WireGuard tunnel.
+func NewUserspace(cfg *config.WireGuard, ifaceCfg *config.Interface, proxyCfg *config.Proxy) *UserspaceTunnel {
+	return &UserspaceTunnel{
+		BaseTunnel: protocols.NewBaseTunnel(),
+		cfg:        cfg,
+		ifaceCfg:   ifaceCfg,
+		proxyCfg:   proxyCfg,
+	}
+}
+
+// Start establishes the userspace WireGuard tunnel and starts the proxies.
+func (t *UserspaceTunnel) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.State() == protocols.StateConnected {
+		return fmt.Errorf("tunnel already connected")
+	}
+
+	t.SetState(protocols.StateConnecting, "Initializing userspace WireGuard tunnel", nil)
+
+	t.ctx, t.cancel = context.WithCancel(ctx)
+
+	localAddr, err := netip.ParsePrefix(t.cfg.Address)
+	if err != nil {
+		t.SetState(protocols.StateError, "Invalid address", err)
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	t.LocalIPAddr = localAddr.Addr()
+
+	dnsServers, err := parseDNSServers(t.cfg.DNS)
+	if err != nil {
+		t.SetState(protocols.StateError, "Invalid DNS", err)
+		return err
+	}
+
+	serverIP, port, err := resolveEndpoint(t.cfg.DialAddress())
+	if err != nil {
+		t.SetState(protocols.StateError, "Failed to resolve server", err)
+		return err
+	}
+	t.ServerIPAddr = serverIP
+
+	// Probing needs raw sockets; without privileges it falls back to the default.
+	t.SetMTU(pmtu.Resolve(t.ifaceCfg.MTU, serverIP, mtuOverhead(t.cfg, serverIP), 0))
+
+	tunDevice, tnet, err := netstack.CreateNetTUN([]netip.Addr{localAddr.Addr()}, dnsServers, t.MTU())
+	if err != nil {
+		t.SetState(protocols.StateError, "Failed to create netstack", err)
+		return fmt.Errorf("failed to create netstack: %w", err)
+	}
+	t.tnet = tnet
+
+	wgLogger := device.NewLogger(device.LogLevelError, "(wireguard) ")
+	t.device = device.NewDevice(tunDevice, newBind(t.cfg), wgLogger)
+
+	uapiConfig, err := generateUAPIConfig(t.cfg, t.ServerIPAddr, port, 0)
+	if err != nil {
+		t.cleanup()
+		t.SetState(protocols.StateError, "Failed to generate config", err)
+		return fmt.Errorf("failed to generate UAPI config: %w", err)
+	}
+
+	if err := t.device.IpcSet(uapiConfig); err != nil {
+		t.cleanup()
+		t.SetState(protocols.StateError, "Failed to apply config", err)
+		return fmt.Errorf("failed to apply config: %w", err)
+	}
+
+	if err := t.device.Up(); err != nil {
+		t.cleanup()
+		t.SetState(protocols.StateError, "Failed to bring device up", err)
+		return fmt.Errorf("failed to bring device up: %w", err)
+	}
+
+	t.proxy = proxy.New(&proxy.Config{
+		SOCKSListen: t.proxyCfg.SOCKSListen,
+		HTTPListen:  t.proxyCfg.HTTPListen,
+		Dial:        t.tnet.DialContext,
+		Username:    t.proxyCfg.Username,
+		Password:    t.proxyCfg.Password,
+	})
+	if err := t.proxy.Start(); err != nil {
+		t.proxy = nil
+		t.cleanup()
+		t.SetState(protocols.StateError, "Failed to start proxy", err)
+		return fmt.Errorf("failed to start proxy: %w", err)
+	}
+
+	t.GatewayIPAddr = calculateGateway(localAddr)
+
+	t.SetState(protocols.StateConnected, "Userspace WireGuard tunnel established", nil)
+
+	return nil
+}
+
+// Stop terminates the userspace WireGuard tunnel.
+func (t *UserspaceTunnel) Stop() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.State() == protocols.StateDisconnected {
+		return nil
+	}
+
+	t.SetState(protocols.StateDisconnecting, "Stopping userspace WireGuard tunnel", nil)
+
+	if t.cancel != nil {
+		t.cancel()
+	}
+
+	t.cleanup()
+
+	t.SetState(protocols.StateDisconnected, "Userspace WireGuard tunnel stopped", nil)
+	t.Close() // Close stateChanges channel so monitorTunnel exits
+
+	return nil
+}
+
+// Reconnect attempts to reconnect the tunnel.
+func (t *UserspaceTunnel) Reconnect() error {
+	t.SetState(protocols.StateReconnecting, "Reconnecting", nil)
+
+	if err := t.Stop(); err != nil {
+		return err
+	}
+
+	// Re-create stateChanges channel (Stop closed it)
+	t.ResetChannel()
+
+	return t.Start(context.Background())
+}
+
+func (t *UserspaceTunnel) cleanup() {
+	if t.proxy != nil {
+		t.proxy.Stop()
+		t.proxy = nil
+	}
+
+	// Closing the device also closes the netstack TUN.
+	if t.device != nil {
+		t.device.Close()
+		t.device = nil
+	}
+
+	t.tnet = nil
+}
+
+// GetDevice returns the underlying WireGuard device.
+func (t *UserspaceTunnel) GetDevice() *device.Device {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.device
+}
+
+// parseDNSServers parses a comma- or space-separated list of DNS server IPs.
+func parseDNSServers(s string) ([]netip.Addr, error) {
+	var servers []netip.Addr
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		addr, err := netip.ParseAddr(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DNS server %s: %w", f, err)
+		}
+		servers = append(servers, addr)
+	}
+	if len(servers) == 0 {
+		logger.Warning("No WireGuard DNS configured, proxy hostname lookups will fail")
+	}
+	return servers, nil
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
+	"runtime"
 	"strings"
 	"sync"
 
@@ -16,6 +17,8 @@ import (
 	"golang.zx2c4.com/wireguard/tun"
 
 	"github.com/user/vpn-client/internal/config"
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/pmtu"
 	"github.com/user/vpn-client/internal/protocols"
 	tunpkg "github.com/user/vpn-client/internal/tun"
 )
@@ -29,6 +32,7 @@ type Tunnel struct {
 	ifaceCfg *config.Interface
 	adapter  *tunpkg.Adapter
 	device   *device.Device
+	uapi     *uapiServer
 	ctx      context.Context
 	cancel   context.CancelFunc
 }
@@ -63,29 +67,21 @@ func (t *Tunnel) Start(ctx context.Context) error {
 	}
 	t.LocalIPAddr = localAddr.Addr()
 
-	// Resolve server endpoint
-	host, port, err := net.SplitHostPort(t.cfg.Peer.Endpoint)
-	if err != nil {
-		t.SetState(protocols.StateError, "Invalid endpoint", err)
-		return fmt.Errorf("invalid endpoint: %w", err)
-	}
-
-	// Resolve hostname to IP
-	ips, err := net.LookupIP(host)
+	// Resolve server endpoint (the relay for stream transports)
+	serverIP, port, err := resolveEndpoint(t.cfg.DialAddress())
 	if err != nil {
 		t.SetState(protocols.StateError, "Failed to resolve server", err)
-		return fmt.Errorf("failed to resolve server: %w", err)
-	}
-	if len(ips) == 0 {
-		t.SetState(protocols.StateError, "No IP addresses found", nil)
-		return fmt.Errorf("no IP addresses found for %s", host)
+		return err
 	}
-	t.ServerIPAddr = ips[0].String()
+	t.ServerIPAddr = serverIP
+
+	// Pick the MTU (probes the path for mtu: auto)
+	t.SetMTU(pmtu.Resolve(t.ifaceCfg.MTU, serverIP, mtuOverhead(t.cfg, serverIP), deviceFwMark(t.cfg)))
 
 	// Create TUN adapter
 	t.adapter, err = tunpkg.New(&tunpkg.Config{
 		Name:   t.ifaceCfg.Name,
-		MTU:    t.ifaceCfg.MTU,
+		MTU:    t.MTU(),
 		Metric: t.ifaceCfg.Metric,
 	})
 	if err != nil {
@@ -107,12 +103,12 @@ func (t *Tunnel) Start(ctx context.Context) error {
 
 	// Create WireGuard device
 	tunDevice := t.adapter.Device()
-	logger := device.NewLogger(device.LogLevelError, "(wireguard) ")
+	wgLogger := device.NewLogger(device.LogLevelError, "(wireguard) ")
 
-	t.device = device.NewDevice(tunDevice, conn.NewDefaultBind(), logger)
+	t.device = device.NewDevice(tunDevice, newBind(t.cfg), wgLogger)
 
 	// Generate UAPI configuration
-	uapiConfig, err := t.generateUAPIConfig(port)
+	uapiConfig, err := generateUAPIConfig(t.cfg, t.ServerIPAddr, port, deviceFwMark(t.cfg))
 	if err != nil {
 		t.cleanup()
 		t.SetState(protocols.StateError, "Failed to generate config", err)
@@ -140,6 +136,11 @@ func (t *Tunnel) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to bring adapter up: %w", err)
 	}
 
+	// Expose the device to wg(8) and monitoring tools
+	if t.uapi, err = startUAPI(t.device, t.adapter.Name()); err != nil {
+		logger.Warning("WireGuard UAPI socket unavailable: %v", err)
+	}
+
 	// Calculate gateway IP (first IP in subnet)
 	t.GatewayIPAddr = calculateGateway(localAddr)
 
@@ -189,6 +190,11 @@ func (t *Tunnel) Reconnect() error {
 }
 
 func (t *Tunnel) cleanup() {
+	if t.uapi != nil {
+		t.uapi.Close()
+		t.uapi = nil
+	}
+
 	if t.device != nil {
 		t.device.Close()
 		t.device = nil
@@ -201,58 +207,141 @@ func (t *Tunnel) cleanup() {
 	}
 }
 
-func (t *Tunnel) generateUAPIConfig(port string) (string, error) {
+// newBind creates the UDP or relay stream bind for cfg, wrapped with
+// obfuscation if configured.
+func newBind(cfg *config.WireGuard) conn.Bind {
+	var bind conn.Bind
+	if cfg.UsesRelay() {
+		bind = newStreamBind(cfg)
+	} else {
+		bind = conn.NewDefaultBind()
+	}
+	if cfg.Obfuscated() {
+		return newObfuscatedBind(bind, cfg)
+	}
+	return bind
+}
+
+// deviceFwMark returns the fwmark for the tunnel's sockets. Only Linux
+// uses it, to keep encrypted packets out of the policy routing table.
+func deviceFwMark(cfg *config.WireGuard) uint32 {
+	if runtime.GOOS != "linux" {
+		return 0
+	}
+	return cfg.Mark()
+}
+
+// resolveEndpoint splits a host:port endpoint and resolves the host to an IP.
+func resolveEndpoint(endpoint string) (ip, port string, err error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve server: %w", err)
+	}
+	if len(ips) == 0 {
+		return "", "", fmt.Errorf("no IP addresses found for %s", host)
+	}
+	return ips[0].String(), port, nil
+}
+
+// generateUAPIConfig builds the wireguard-go UAPI configuration for cfg.
+func generateUAPIConfig(cfg *config.WireGuard, serverIP, port string, fwmark uint32) (string, error) {
 	// Decode private key
-	privateKey, err := base64.StdEncoding.DecodeString(t.cfg.PrivateKey)
+	privateKey, err := base64.StdEncoding.DecodeString(cfg.PrivateKey)
 	if err != nil {
 		return "", fmt.Errorf("invalid private key: %w", err)
 	}
 
 	// Decode public key
-	publicKey, err := base64.StdEncoding.DecodeString(t.cfg.Peer.PublicKey)
+	publicKey, err := base64.StdEncoding.DecodeString(cfg.Peer.PublicKey)
 	if err != nil {
 		return "", fmt.Errorf("invalid public key: %w", err)
 	}
 
-	var config strings.Builder
+	var uapi strings.Builder
 
 	// Private key
-	config.WriteString(fmt.Sprintf("private_key=%s\n", hex.EncodeToString(privateKey)))
+	uapi.WriteString(fmt.Sprintf("private_key=%s\n", hex.EncodeToString(privateKey)))
+
+	// Firewall mark (0 = none)
+	if fwmark != 0 {
+		uapi.WriteString(fmt.Sprintf("fwmark=%d\n", fwmark))
+	}
 
 	// Peer configuration
-	config.WriteString(fmt.Sprintf("public_key=%s\n", hex.EncodeToString(publicKey)))
+	uapi.WriteString(fmt.Sprintf("public_key=%s\n", hex.EncodeToString(publicKey)))
 
 	// Preshared key (if present)
-	if t.cfg.Peer.PresharedKey != "" {
-		psk, err := base64.StdEncoding.DecodeString(t.cfg.Peer.PresharedKey)
+	if cfg.Peer.PresharedKey != "" {
+		psk, err := base64.StdEncoding.DecodeString(cfg.Peer.PresharedKey)
 		if err != nil {
 			return "", fmt.Errorf("invalid preshared key: %w", err)
 		}
-		config.WriteString(fmt.Sprintf("preshared_key=%s\n", hex.EncodeToString(psk)))
+		uapi.WriteString(fmt.Sprintf("preshared_key=%s\n", hex.EncodeToString(psk)))
 	}
 
 	// Endpoint
-	config.WriteString(fmt.Sprintf("endpoint=%s:%s\n", t.ServerIPAddr, port))
+	uapi.WriteString(fmt.Sprintf("endpoint=%s\n", net.JoinHostPort(serverIP, port)))
 
 	// Allowed IPs - only route traffic for configured IPs
 	// For split tunneling, we DON'T set 0.0.0.0/0
 	// The actual routing is handled by the routing module
 	// Here we allow all traffic that arrives at the tunnel
-	config.WriteString("allowed_ip=0.0.0.0/0\n")
-	config.WriteString("allowed_ip=::/0\n")
+	uapi.WriteString("allowed_ip=0.0.0.0/0\n")
+	uapi.WriteString("allowed_ip=::/0\n")
 
 	// Persistent keepalive
-	if t.cfg.Peer.PersistentKeepalive > 0 {
-		config.WriteString(fmt.Sprintf("persistent_keepalive_interval=%d\n", t.cfg.Peer.PersistentKeepalive))
+	if cfg.Peer.PersistentKeepalive > 0 {
+		uapi.WriteString(fmt.Sprintf("persistent_keepalive_interval=%d\n", cfg.Peer.PersistentKeepalive))
 	}
 
-	return config.String(), nil
+	return uapi.String(), nil
 }
 
 func (t *Tunnel) monitor() {
 	<-t.ctx.Done()
 }
 
+// ReprobeMTU re-runs path MTU discovery for mtu: auto and applies the result.
+func (t *Tunnel) ReprobeMTU() (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.adapter == nil {
+		return 0, fmt.Errorf("tunnel not connected")
+	}
+	if !t.ifaceCfg.MTU.IsAuto() {
+		return t.MTU(), nil
+	}
+
+	mtu := pmtu.Resolve(t.ifaceCfg.MTU, t.ServerIPAddr, mtuOverhead(t.cfg, t.ServerIPAddr), deviceFwMark(t.cfg))
+	if mtu != t.MTU() {
+		if err := t.adapter.SetMTU(mtu); err != nil {
+			return 0, err
+		}
+		t.SetMTU(mtu)
+	}
+	return mtu, nil
+}
+
+// mtuOverhead returns the bytes WireGuard adds to each tunneled packet:
+// outer IP and UDP headers, message header and authentication tag.
+// Stream transports carry datagrams inside TCP, which segments them, so
+// they add nothing to the path MTU constraint.
+func mtuOverhead(cfg *config.WireGuard, serverIP string) int {
+	if cfg.UsesRelay() {
+		return 0
+	}
+	if addr, err := netip.ParseAddr(serverIP); err == nil && addr.Is6() && !addr.Is4In6() {
+		return 80
+	}
+	return 60
+}
+
 // GetDevice returns the underlying WireGuard device.
 func (t *Tunnel) GetDevice() *device.Device {
 	t.mu.Lock()
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/user/vpn-client/internal/logger"
+)
+
+// handleHTTP serves a single HTTP proxy client. CONNECT requests are
+// tunneled; plain absolute-URI requests are forwarded through the tunnel.
+func (s *Server) handleHTTP(c net.Conn) {
+	br := bufio.NewReader(c)
+	transport := &http.Transport{
+		DialContext:         s.cfg.Dial,
+		DisableKeepAlives:   true,
+		TLSHandshakeTimeout: 15 * time.Second,
+	}
+	defer transport.CloseIdleConnections()
+
+	for {
+		c.SetReadDeadline(time.Now().Add(60 * time.Second))
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+		c.SetReadDeadline(time.Time{})
+
+		if !s.httpAuthenticate(req) {
+			logger.Warning("HTTP proxy authentication failed from %s", c.RemoteAddr())
+			writeHTTPAuthRequired(c)
+			return
+		}
+
+		if req.Method == http.MethodConnect {
+			s.handleConnect(c, br, req.Host)
+			return
+		}
+
+		if !req.URL.IsAbs() {
+			writeHTTPStatus(c, http.StatusBadRequest)
+			return
+		}
+
+		req.RequestURI = ""
+		req.Header.Del("Proxy-Connection")
+		req.Header.Del("Proxy-Authorization")
+
+		resp, err := transport.RoundTrip(req.WithContext(s.ctx))
+		if err != nil {
+			logger.Debug("HTTP proxy request to %s failed: %v", req.URL.Host, err)
+			writeHTTPStatus(c, http.StatusBadGateway)
+			return
+		}
+		err = resp.Write(c)
+		resp.Body.Close()
+		if err != nil || req.Close || resp.Close {
+			return
+		}
+	}
+}
+
+// handleConnect tunnels c to target. br is the reader the request was
+// parsed from: data the client sent right after CONNECT, such as a TLS
+// ClientHello, may already be buffered there.
+func (s *Server) handleConnect(c net.Conn, br *bufio.Reader, target string) {
+	remote, err := s.cfg.Dial(s.ctx, "tcp", target)
+	if err != nil {
+		logger.Debug("HTTP CONNECT %s failed: %v", target, err)
+		writeHTTPStatus(c, http.StatusBadGateway)
+		return
+	}
+	defer remote.Close()
+
+	if _, err := c.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		return
+	}
+	if n := br.Buffered(); n > 0 {
+		early, _ := br.Peek(n)
+		if _, err := remote.Write(early); err != nil {
+			return
+		}
+	}
+
+	Relay(c, remote)
+}
+
+// httpAuthenticate checks the Proxy-Authorization header against the
+// configured credentials.
+func (s *Server) httpAuthenticate(req *http.Request) bool {
+	if s.cfg.Username == "" {
+		return true
+	}
+	// Reuse the Authorization parser on the proxy header
+	r := &http.Request{Header: http.Header{"Authorization": req.Header.Values("Proxy-Authorization")}}
+	user, pass, ok := r.BasicAuth()
+	return ok && s.checkCredentials(user, pass)
+}
+
+func writeHTTPAuthRequired(c net.Conn) {
+	resp := &http.Response{
+		StatusCode: http.StatusProxyAuthRequired,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Proxy-Authenticate": {`Basic realm="vpn-client"`}},
+		Close:      true,
+	}
+	resp.Write(c)
+}
+
+func writeHTTPStatus(c net.Conn, code int) {
+	resp := &http.Response{
+		StatusCode: code,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Close:      true,
+	}
+	resp.Write(c)
+}
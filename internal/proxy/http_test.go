@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestHTTPConnectEarlyData checks that data sent in the same write as the
+// CONNECT request reaches the target.
+func TestHTTPConnectEarlyData(t *testing.T) {
+	client, target := net.Pipe()
+	defer target.Close()
+
+	s := New(&Config{
+		HTTPListen: "127.0.0.1:0",
+		Dial: func(context.Context, string, string) (net.Conn, error) {
+			return client, nil
+		},
+	})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	c, err := net.Dial("tcp", s.listeners[0].Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	target.SetDeadline(time.Now().Add(5 * time.Second))
+
+	const early = "client hello"
+	if _, err := io.WriteString(c, "CONNECT example.com:443 HTTP/1.1\r\nHost: example.com:443\r\n\r\n"+early); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(c), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("CONNECT returned %s", resp.Status)
+	}
+
+	got := make([]byte, len(early))
+	if _, err := io.ReadFull(target, got); err != nil {
+		t.Fatalf("early data lost: %v", err)
+	}
+	if string(got) != early {
+		t.Fatalf("target got %q, want %q", got, early)
+	}
+}
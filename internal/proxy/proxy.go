@@ -0,0 +1,150 @@
+// Package proxy implements local SOCKS5 and HTTP CONNECT proxies that dial
+// through a userspace tunnel.
+package proxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/user/vpn-client/internal/logger"
+)
+
+// DialFunc opens an outbound connection through the tunnel.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Config represents proxy server configuration.
+type Config struct {
+	SOCKSListen string // empty = SOCKS5 disabled
+	HTTPListen  string // empty = HTTP proxy disabled
+	Dial        DialFunc
+
+	// Username and Password are required from clients when set.
+	Username string
+	Password string
+}
+
+// Server runs the configured local proxies.
+type Server struct {
+	mu        sync.Mutex
+	cfg       *Config
+	listeners []net.Listener
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// New creates a new proxy server.
+func New(cfg *Config) *Server {
+	return &Server{cfg: cfg}
+}
+
+// Start opens the listeners and begins accepting connections.
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cfg.Dial == nil {
+		return fmt.Errorf("no dial function configured")
+	}
+	if s.cfg.SOCKSListen == "" && s.cfg.HTTPListen == "" {
+		return fmt.Errorf("no proxy listen address configured")
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	if s.cfg.SOCKSListen != "" {
+		ln, err := net.Listen("tcp", s.cfg.SOCKSListen)
+		if err != nil {
+			s.closeUnsafe()
+			return fmt.Errorf("failed to listen on %s: %w", s.cfg.SOCKSListen, err)
+		}
+		s.listeners = append(s.listeners, ln)
+		s.serve(ln, s.handleSOCKS)
+		logger.Info("SOCKS5 proxy listening on %s", ln.Addr())
+	}
+
+	if s.cfg.HTTPListen != "" {
+		ln, err := net.Listen("tcp", s.cfg.HTTPListen)
+		if err != nil {
+			s.closeUnsafe()
+			return fmt.Errorf("failed to listen on %s: %w", s.cfg.HTTPListen, err)
+		}
+		s.listeners = append(s.listeners, ln)
+		s.serve(ln, s.handleHTTP)
+		logger.Info("HTTP proxy listening on %s", ln.Addr())
+	}
+
+	return nil
+}
+
+// Stop closes the listeners and waits for the accept loops to exit.
+// Connections already being relayed are closed when their dialer closes.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	s.closeUnsafe()
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) closeUnsafe() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	for _, ln := range s.listeners {
+		ln.Close()
+	}
+	s.listeners = nil
+}
+
+func (s *Server) serve(ln net.Listener, handle func(net.Conn)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer logger.Recover("proxyConn")
+				defer c.Close()
+				handle(c)
+			}()
+		}
+	}()
+}
+
+// checkCredentials reports whether user and pass match the configured
+// credentials, or whether no credentials are configured.
+func (s *Server) checkCredentials(user, pass string) bool {
+	if s.cfg.Username == "" {
+		return true
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(s.cfg.Username))
+	passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(s.cfg.Password))
+	return userOK&passOK == 1
+}
+
+// Relay copies data in both directions until either side closes.
+func Relay(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	cp := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+			cw.CloseWrite()
+		} else {
+			dst.Close()
+		}
+		done <- struct{}{}
+	}
+	go cp(a, b)
+	go cp(b, a)
+	<-done
+	<-done
+}
@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/user/vpn-client/internal/logger"
+)
+
+// SOCKS5 protocol constants (RFC 1928).
+const (
+	socksVersion      = 0x05
+	socksAuthNone     = 0x00
+	socksAuthPassword = 0x02 // RFC 1929
+	socksAuthNoAccept = 0xff
+	socksCmdConnect   = 0x01
+	socksAtypIPv4     = 0x01
+	socksAtypDomain   = 0x03
+	socksAtypIPv6     = 0x04
+
+	socksRepSuccess         = 0x00
+	socksRepGeneralFailure  = 0x01
+	socksRepHostUnreachable = 0x04
+	socksRepCmdUnsupported  = 0x07
+	socksRepAtypUnsupported = 0x08
+)
+
+// handleSOCKS serves a single SOCKS5 client. Only the CONNECT command is
+// supported, without authentication or, when credentials are configured,
+// with username/password authentication.
+func (s *Server) handleSOCKS(c net.Conn) {
+	c.SetDeadline(time.Now().Add(30 * time.Second))
+
+	// Greeting: VER NMETHODS METHODS...
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(c, hdr); err != nil {
+		return
+	}
+	if hdr[0] != socksVersion {
+		return
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(c, methods); err != nil {
+		return
+	}
+	want := byte(socksAuthNone)
+	if s.cfg.Username != "" {
+		want = socksAuthPassword
+	}
+	method := byte(socksAuthNoAccept)
+	for _, m := range methods {
+		if m == want {
+			method = want
+			break
+		}
+	}
+	if _, err := c.Write([]byte{socksVersion, method}); err != nil || method == socksAuthNoAccept {
+		return
+	}
+	if method == socksAuthPassword && !s.socksAuthenticate(c) {
+		return
+	}
+
+	// Request: VER CMD RSV ATYP DST.ADDR DST.PORT
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(c, req); err != nil {
+		return
+	}
+	if req[1] != socksCmdConnect {
+		socksReply(c, socksRepCmdUnsupported)
+		return
+	}
+
+	target, err := readSOCKSAddr(c, req[3])
+	if err != nil {
+		socksReply(c, socksRepAtypUnsupported)
+		return
+	}
+
+	remote, err := s.cfg.Dial(s.ctx, "tcp", target)
+	if err != nil {
+		logger.Debug("SOCKS5 dial %s failed: %v", target, err)
+		socksReply(c, socksRepHostUnreachable)
+		return
+	}
+	defer remote.Close()
+
+	if err := socksReply(c, socksRepSuccess); err != nil {
+		return
+	}
+	c.SetDeadline(time.Time{})
+
+	Relay(c, remote)
+}
+
+// socksAuthenticate runs the username/password subnegotiation of RFC 1929:
+// VER ULEN UNAME PLEN PASSWD, answered with VER STATUS.
+func (s *Server) socksAuthenticate(c net.Conn) bool {
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(c, hdr); err != nil || hdr[0] != 0x01 {
+		return false
+	}
+	user := make([]byte, hdr[1])
+	if _, err := io.ReadFull(c, user); err != nil {
+		return false
+	}
+	if _, err := io.ReadFull(c, hdr[:1]); err != nil {
+		return false
+	}
+	pass := make([]byte, hdr[0])
+	if _, err := io.ReadFull(c, pass); err != nil {
+		return false
+	}
+
+	if !s.checkCredentials(string(user), string(pass)) {
+		logger.Warning("SOCKS5 authentication failed from %s", c.RemoteAddr())
+		c.Write([]byte{0x01, 0x01})
+		return false
+	}
+	_, err := c.Write([]byte{0x01, 0x00})
+	return err == nil
+}
+
+// readSOCKSAddr reads DST.ADDR and DST.PORT for the given address type.
+func readSOCKSAddr(r io.Reader, atyp byte) (string, error) {
+	var host string
+	switch atyp {
+	case socksAtypIPv4:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case socksAtypIPv6:
+		ip := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case socksAtypDomain:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(r, l); err != nil {
+			return "", err
+		}
+		name := make([]byte, l[0])
+		if _, err := io.ReadFull(r, name); err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		return "", fmt.Errorf("unsupported address type %d", atyp)
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(r, port); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// socksReply sends a reply with an all-zero bound address.
+func socksReply(w io.Writer, rep byte) error {
+	_, err := w.Write([]byte{socksVersion, rep, 0x00, socksAtypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
@@ -0,0 +1,45 @@
+// Package relay carries WireGuard datagrams over TCP or WebSocket streams.
+//
+// Each datagram is sent as a 2-byte big-endian length followed by the
+// payload. The client side lives in the wireguard protocol package; the
+// server side (Server) accepts streams and forwards their datagrams to a
+// fixed WireGuard UDP endpoint.
+package relay
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// MaxFrameSize is the largest datagram that fits in a frame.
+const MaxFrameSize = 65535
+
+// WriteFrame writes p as a single length-prefixed frame.
+func WriteFrame(w io.Writer, p []byte) error {
+	if len(p) > MaxFrameSize {
+		return fmt.Errorf("frame too large: %d bytes", len(p))
+	}
+	// One Write per frame keeps WebSocket messages aligned with frames.
+	frame := make([]byte, 2+len(p))
+	binary.BigEndian.PutUint16(frame, uint16(len(p)))
+	copy(frame[2:], p)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadFrame reads a single frame into buf and returns the payload length.
+func ReadFrame(r io.Reader, buf []byte) (int, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, err
+	}
+	n := int(binary.BigEndian.Uint16(hdr[:]))
+	if n > len(buf) {
+		return 0, fmt.Errorf("frame of %d bytes exceeds buffer", n)
+	}
+	if _, err := io.ReadFull(r, buf[:n]); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
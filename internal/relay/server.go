@@ -0,0 +1,212 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// udpIdleTimeout closes a client's UDP leg when the server stays silent
+// longer than any WireGuard keepalive or rekey interval.
+const udpIdleTimeout = 5 * time.Minute
+
+// ServerConfig represents relay server configuration.
+type ServerConfig struct {
+	Listen    string // TCP listen address, e.g. ":443"
+	Target    string // WireGuard UDP endpoint, e.g. "127.0.0.1:51820"
+	WSPath    string // serve WebSocket on this path; empty = raw TCP
+	TLSCert   string // optional TLS certificate (WebSocket only)
+	TLSKey    string // optional TLS key (WebSocket only)
+	Logger    *log.Logger
+	OnStarted func(addr net.Addr) // optional, called once listening
+}
+
+// Server accepts relay streams and forwards their datagrams to Target.
+type Server struct {
+	cfg    *ServerConfig
+	ln     net.Listener
+	http   *http.Server
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+// NewServer creates a new relay server.
+func NewServer(cfg *ServerConfig) *Server {
+	if cfg.Logger == nil {
+		cfg.Logger = log.Default()
+	}
+	return &Server{cfg: cfg, conns: make(map[net.Conn]struct{})}
+}
+
+// ListenAndServe listens on cfg.Listen and serves until Close is called.
+func (s *Server) ListenAndServe() error {
+	if _, err := net.ResolveUDPAddr("udp", s.cfg.Target); err != nil {
+		return fmt.Errorf("invalid target %s: %w", s.cfg.Target, err)
+	}
+
+	ln, err := net.Listen("tcp", s.cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.cfg.Listen, err)
+	}
+	s.mu.Lock()
+	s.ln = ln
+	s.mu.Unlock()
+
+	if s.cfg.OnStarted != nil {
+		s.cfg.OnStarted(ln.Addr())
+	}
+
+	if s.cfg.WSPath != "" {
+		return s.serveWebSocket(ln)
+	}
+	return s.serveTCP(ln)
+}
+
+// Close stops the server and waits for active streams to finish.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	ln, hs := s.ln, s.http
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	if hs != nil {
+		hs.Shutdown(context.Background())
+	}
+	if ln != nil {
+		ln.Close()
+	}
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) serveTCP(ln net.Listener) error {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			return err
+		}
+		if tc, ok := c.(*net.TCPConn); ok {
+			tc.SetNoDelay(true)
+		}
+		go s.handle(c, c.RemoteAddr().String())
+	}
+}
+
+func (s *Server) serveWebSocket(ln net.Listener) error {
+	mux := http.NewServeMux()
+	mux.Handle(s.cfg.WSPath, websocket.Server{
+		// Accept any Origin; clients are not browsers.
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			ws.PayloadType = websocket.BinaryFrame
+			s.handle(ws, ws.Request().RemoteAddr)
+		},
+	})
+
+	hs := &http.Server{Handler: mux, ReadHeaderTimeout: 15 * time.Second}
+	s.mu.Lock()
+	s.http = hs
+	s.mu.Unlock()
+
+	var err error
+	if s.cfg.TLSCert != "" {
+		err = hs.ServeTLS(ln, s.cfg.TLSCert, s.cfg.TLSKey)
+	} else {
+		err = hs.Serve(ln)
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// track registers c as active; it returns false if the server is closing.
+func (s *Server) track(c net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[c] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) untrack(c net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+	s.wg.Done()
+}
+
+// handle pumps datagrams between one client stream and its own UDP socket,
+// so the WireGuard server sees each client as a distinct source port.
+func (s *Server) handle(c net.Conn, remote string) {
+	defer c.Close()
+	if !s.track(c) {
+		return
+	}
+	defer s.untrack(c)
+
+	udp, err := net.Dial("udp", s.cfg.Target)
+	if err != nil {
+		s.cfg.Logger.Printf("%s: failed to dial target: %v", remote, err)
+		return
+	}
+	defer udp.Close()
+
+	s.cfg.Logger.Printf("%s: stream opened", remote)
+	defer s.cfg.Logger.Printf("%s: stream closed", remote)
+
+	// UDP -> stream
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer c.Close()
+		buf := make([]byte, MaxFrameSize)
+		for {
+			udp.SetReadDeadline(time.Now().Add(udpIdleTimeout))
+			n, err := udp.Read(buf)
+			if err != nil {
+				return
+			}
+			if err := WriteFrame(c, buf[:n]); err != nil {
+				return
+			}
+		}
+	}()
+
+	// stream -> UDP
+	buf := make([]byte, MaxFrameSize)
+	for {
+		n, err := ReadFrame(c, buf)
+		if err != nil {
+			break
+		}
+		if _, err := udp.Write(buf[:n]); err != nil {
+			break
+		}
+	}
+	udp.Close()
+	<-done
+}
@@ -17,10 +17,17 @@ type Route struct {
 	Gateway     netip.Addr
 	Interface   uint32 // Interface index
 	Metric      int
-	Source      string // "static", "domain", "manual", "tunnel"
+	Source      string // "static", "domain", "manual", "tunnel", "pushed"
 	Domain      string // Original domain if resolved from domain
 }
 
+// onLink reports whether the route must point at the interface instead of
+// the gateway: there is none, or it is of the other address family (IPv6
+// routes over an IPv4-addressed tunnel).
+func (r *Route) onLink() bool {
+	return !r.Gateway.IsValid() || r.Gateway.Is4() != r.Destination.Addr().Is4()
+}
+
 // Manager manages routing table entries for split tunneling.
 type Manager struct {
 	mu             sync.Mutex
@@ -30,10 +37,20 @@ type Manager struct {
 	originalGW     netip.Addr
 	originalIfIdx  uint32
 	domainResolver *DomainResolver
+	policy         *policyRouting
 	ctx            context.Context
 	cancel         context.CancelFunc
 }
 
+// policyRouting records the fwmark-based full-tunnel setup (Linux).
+type policyRouting struct {
+	ifName       string
+	fwmark       uint32
+	table        uint32
+	families     []string // "-4", "-6": families whose rules were added
+	srcValidMark string   // previous net.ipv4.conf.all.src_valid_mark
+}
+
 // NewManager creates a new routing manager.
 func NewManager() *Manager {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -217,6 +234,14 @@ func (m *Manager) GetRoutes() []*Route {
 func (m *Manager) Close() {
 	m.cancel()
 	m.RemoveAllRoutes()
+	m.DisablePolicyRouting()
+}
+
+// PolicyRoutingEnabled reports whether fwmark policy routing is active.
+func (m *Manager) PolicyRoutingEnabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.policy != nil
 }
 
 // GetVPNGateway returns the VPN gateway address.
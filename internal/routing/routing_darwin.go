@@ -4,6 +4,7 @@ package routing
 
 import (
 	"fmt"
+	"net"
 	"net/netip"
 	"os/exec"
 	"strings"
@@ -97,6 +98,13 @@ func (m *Manager) addSystemRoute(route *Route) error {
 	gateway := route.Gateway.String()
 
 	cmd := exec.Command("route", "add", "-net", dest, gateway)
+	if route.onLink() {
+		ifi, err := net.InterfaceByIndex(int(route.Interface))
+		if err != nil {
+			return fmt.Errorf("failed to add route: no interface for %s: %w", dest, err)
+		}
+		cmd = exec.Command("route", "add", familyFlag(route.Destination), "-net", dest, "-interface", ifi.Name)
+	}
 	if out, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to add route: %w: %s", err, string(out))
 	}
@@ -107,11 +115,19 @@ func (m *Manager) addSystemRoute(route *Route) error {
 // removeSystemRoute removes a route from the macOS routing table.
 func (m *Manager) removeSystemRoute(route *Route) error {
 	dest := route.Destination.String()
-	cmd := exec.Command("route", "delete", "-net", dest)
+	cmd := exec.Command("route", "delete", familyFlag(route.Destination), "-net", dest)
 	cmd.CombinedOutput()
 	return nil
 }
 
+// familyFlag returns the route(8) address family flag for prefix.
+func familyFlag(prefix netip.Prefix) string {
+	if prefix.Addr().Is4() {
+		return "-inet"
+	}
+	return "-inet6"
+}
+
 // EnsureVPNServerRoute ensures the VPN server IP is routed via the original gateway (macOS).
 func (m *Manager) EnsureVPNServerRoute(serverIP string) error {
 	addr, err := netip.ParseAddr(serverIP)
@@ -134,3 +150,14 @@ func (m *Manager) RemoveVPNServerRoute(serverIP string) error {
 	cmd.CombinedOutput()
 	return nil
 }
+
+// PolicyRoutingSupported reports whether EnablePolicyRouting is available.
+const PolicyRoutingSupported = false
+
+// EnablePolicyRouting is only implemented on Linux.
+func (m *Manager) EnablePolicyRouting(ifName string, fwmark uint32) error {
+	return fmt.Errorf("policy routing is not supported on this platform")
+}
+
+// DisablePolicyRouting is a no-op on this platform.
+func (m *Manager) DisablePolicyRouting() {}
@@ -4,6 +4,7 @@ package routing
 
 import (
 	"fmt"
+	"net"
 	"net/netip"
 	"os/exec"
 	"strings"
@@ -94,6 +95,13 @@ func (m *Manager) addSystemRoute(route *Route) error {
 	gateway := route.Gateway.String()
 
 	args := []string{"route", "add", dest, "via", gateway}
+	if route.onLink() {
+		ifi, err := net.InterfaceByIndex(int(route.Interface))
+		if err != nil {
+			return fmt.Errorf("failed to add route: no interface for %s: %w", dest, err)
+		}
+		args = []string{"route", "add", dest, "dev", ifi.Name}
+	}
 	if route.Metric > 0 {
 		args = append(args, "metric", fmt.Sprintf("%d", route.Metric))
 	}
@@ -139,3 +147,101 @@ func (m *Manager) RemoveVPNServerRoute(serverIP string) error {
 	cmd.CombinedOutput()
 	return nil
 }
+
+// PolicyRoutingSupported reports whether EnablePolicyRouting is available.
+const PolicyRoutingSupported = true
+
+// EnablePolicyRouting routes all traffic through ifName the way wg-quick
+// does: a default route in a dedicated table, selected for every packet
+// that does not carry fwmark. The tunnel's own encrypted packets are
+// marked, so they keep using the main table and whatever default gateway
+// is current, without a /32 server route. suppress_prefixlength 0 keeps
+// more specific main-table routes (LAN, split routes) in effect.
+func (m *Manager) EnablePolicyRouting(ifName string, fwmark uint32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.policy != nil {
+		return fmt.Errorf("policy routing already enabled")
+	}
+
+	p := &policyRouting{ifName: ifName, fwmark: fwmark, table: fwmark}
+	mark := fmt.Sprintf("%d", p.fwmark)
+	table := fmt.Sprintf("%d", p.table)
+
+	for _, family := range []string{"-4", "-6"} {
+		defaultDst := "0.0.0.0/0"
+		if family == "-6" {
+			defaultDst = "::/0"
+		}
+
+		steps := [][]string{
+			{family, "route", "add", defaultDst, "dev", ifName, "table", table},
+			{family, "rule", "add", "not", "fwmark", mark, "table", table},
+			{family, "rule", "add", "table", "main", "suppress_prefixlength", "0"},
+		}
+		var err error
+		for _, args := range steps {
+			if out, cmdErr := exec.Command("ip", args...).CombinedOutput(); cmdErr != nil {
+				err = fmt.Errorf("ip %s: %w: %s", strings.Join(args, " "), cmdErr, strings.TrimSpace(string(out)))
+				break
+			}
+		}
+		if err != nil {
+			removePolicyFamily(family, p)
+			if family == "-6" {
+				// IPv6 may be disabled on the host; IPv4 is what matters.
+				continue
+			}
+			return fmt.Errorf("failed to enable policy routing: %w", err)
+		}
+		p.families = append(p.families, family)
+	}
+
+	// Reverse path filtering must accept replies to marked packets.
+	p.srcValidMark = readSysctl(srcValidMarkSysctl)
+	if p.srcValidMark != "1" {
+		exec.Command("sysctl", "-q", "-w", srcValidMarkSysctl+"=1").CombinedOutput()
+	}
+
+	m.policy = p
+	return nil
+}
+
+// DisablePolicyRouting removes the rules and table added by
+// EnablePolicyRouting.
+func (m *Manager) DisablePolicyRouting() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.policy
+	if p == nil {
+		return
+	}
+	for _, family := range p.families {
+		removePolicyFamily(family, p)
+	}
+	if p.srcValidMark != "" && p.srcValidMark != "1" {
+		exec.Command("sysctl", "-q", "-w", srcValidMarkSysctl+"="+p.srcValidMark).CombinedOutput()
+	}
+	m.policy = nil
+}
+
+const srcValidMarkSysctl = "net.ipv4.conf.all.src_valid_mark"
+
+// removePolicyFamily deletes one address family's rules and table.
+func removePolicyFamily(family string, p *policyRouting) {
+	mark := fmt.Sprintf("%d", p.fwmark)
+	table := fmt.Sprintf("%d", p.table)
+	exec.Command("ip", family, "rule", "delete", "not", "fwmark", mark, "table", table).CombinedOutput()
+	exec.Command("ip", family, "rule", "delete", "table", "main", "suppress_prefixlength", "0").CombinedOutput()
+	exec.Command("ip", family, "route", "flush", "table", table).CombinedOutput()
+}
+
+func readSysctl(name string) string {
+	out, err := exec.Command("sysctl", "-n", name).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -79,7 +79,7 @@ func (m *Manager) addSystemRoute(route *Route) error {
 	isIPv6 := route.Destination.Addr().Is6()
 
 	gateway := route.Gateway.String()
-	useOnLink := !route.Gateway.IsValid()
+	useOnLink := route.onLink()
 	if useOnLink {
 		if isIPv6 {
 			gateway = "::"
@@ -169,3 +169,14 @@ func (m *Manager) RemoveVPNServerRoute(serverIP string) error {
 	cmd.CombinedOutput()
 	return nil
 }
+
+// PolicyRoutingSupported reports whether EnablePolicyRouting is available.
+const PolicyRoutingSupported = false
+
+// EnablePolicyRouting is only implemented on Linux.
+func (m *Manager) EnablePolicyRouting(ifName string, fwmark uint32) error {
+	return fmt.Errorf("policy routing is not supported on this platform")
+}
+
+// DisablePolicyRouting is a no-op on this platform.
+func (m *Manager) DisablePolicyRouting() {}
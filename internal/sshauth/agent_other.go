@@ -0,0 +1,18 @@
+//go:build !windows
+
+package sshauth
+
+import (
+	"io"
+	"net"
+	"os"
+)
+
+// dialAgent connects to the ssh-agent socket from SSH_AUTH_SOCK.
+func dialAgent() (io.ReadWriteCloser, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, errNoAgent
+	}
+	return net.Dial("unix", sock)
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package sshauth
+
+import (
+	"io"
+	"os"
+)
+
+// agentPipe is the named pipe of the Windows OpenSSH agent service.
+const agentPipe = `\\.\pipe\openssh-ssh-agent`
+
+// dialAgent connects to the agent pipe from SSH_AUTH_SOCK or to the
+// OpenSSH agent service.
+func dialAgent() (io.ReadWriteCloser, error) {
+	pipe := os.Getenv("SSH_AUTH_SOCK")
+	if pipe == "" {
+		pipe = agentPipe
+	}
+	f, err := os.OpenFile(pipe, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return nil, errNoAgent
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,247 @@
+// Package sshauth builds SSH client authentication from the configuration:
+// private keys (optionally encrypted, with OpenSSH user certificates),
+// ssh-agent, keyboard-interactive and password, tried in a fixed order.
+package sshauth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+)
+
+// Method names accepted in Options.Order.
+const (
+	MethodPublicKey           = "publickey"
+	MethodKeyboardInteractive = "keyboard-interactive"
+	MethodPassword            = "password"
+)
+
+// DefaultOrder is used when no order is configured.
+var DefaultOrder = []string{MethodPublicKey, MethodKeyboardInteractive, MethodPassword}
+
+// Options describes the configured credentials.
+type Options struct {
+	KeyPath        string // private key, ~ is expanded
+	CertPath       string // OpenSSH user certificate, default KeyPath + "-cert.pub"
+	Passphrase     string // for an encrypted KeyPath, asked for when empty
+	Password       string
+	IdentitiesOnly bool     // do not offer ssh-agent keys
+	Order          []string // method order, DefaultOrder if empty
+
+	// Prompt asks for passphrases and keyboard-interactive answers. nil
+	// limits authentication to configured secrets.
+	Prompt protocols.PromptFunc
+}
+
+// passphrases remembers passphrases entered for keys during this run, so
+// reconnects do not ask again.
+var passphrases sync.Map
+
+// Methods returns the authentication methods in the configured order and a
+// function releasing the ssh-agent connection once the handshake is done.
+func Methods(opts Options) ([]ssh.AuthMethod, func(), error) {
+	var signers []ssh.Signer
+	if opts.KeyPath != "" {
+		s, err := keySigners(opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		signers = append(signers, s...)
+	}
+
+	closeAgent := func() {}
+	if !opts.IdentitiesOnly {
+		if conn, err := dialAgent(); err == nil {
+			keys, err := agent.NewClient(conn).Signers()
+			if err != nil {
+				logger.Warning("SSH: failed to list ssh-agent keys: %v", err)
+			}
+			if len(keys) > 0 {
+				logger.Debug("SSH: %d key(s) from ssh-agent", len(keys))
+			}
+			signers = append(signers, keys...)
+			closeAgent = func() { conn.Close() }
+		} else if !errors.Is(err, errNoAgent) {
+			logger.Debug("SSH: ssh-agent unavailable: %v", err)
+		}
+	}
+
+	order := opts.Order
+	if len(order) == 0 {
+		order = DefaultOrder
+	}
+
+	var methods []ssh.AuthMethod
+	for _, m := range order {
+		switch m {
+		case MethodPublicKey:
+			// One method for all keys: the client tries each method
+			// name only once.
+			if len(signers) > 0 {
+				methods = append(methods, ssh.PublicKeys(signers...))
+			}
+		case MethodKeyboardInteractive:
+			if opts.Password != "" || opts.Prompt != nil {
+				methods = append(methods, ssh.KeyboardInteractive(keyboardInteractive(opts)))
+			}
+		case MethodPassword:
+			if opts.Password != "" {
+				methods = append(methods, ssh.Password(opts.Password))
+			}
+		default:
+			closeAgent()
+			return nil, nil, fmt.Errorf("unknown authentication method %q", m)
+		}
+	}
+	if len(methods) == 0 {
+		closeAgent()
+		return nil, nil, fmt.Errorf("no authentication method available (key_path, password or ssh-agent required)")
+	}
+	return methods, closeAgent, nil
+}
+
+// ExpandPath strips surrounding quotes and expands a leading ~.
+func ExpandPath(path string) string {
+	if len(path) > 2 && path[0] == '"' && path[len(path)-1] == '"' {
+		path = path[1 : len(path)-1]
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// keySigners loads the private key and, if present, its certificate. The
+// certificate signer comes first so servers trusting the CA accept it.
+func keySigners(opts Options) ([]ssh.Signer, error) {
+	keyPath := ExpandPath(opts.KeyPath)
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key '%s': %w", keyPath, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		signer, err = decryptKey(keyPath, key, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	certPath := ExpandPath(opts.CertPath)
+	if certPath == "" {
+		certPath = keyPath + "-cert.pub"
+		if _, err := os.Stat(certPath); err != nil {
+			return []ssh.Signer{signer}, nil
+		}
+	}
+	certSigner, err := certificateSigner(certPath, signer)
+	if err != nil {
+		return nil, err
+	}
+	return []ssh.Signer{certSigner, signer}, nil
+}
+
+// decryptKey decrypts a passphrase-protected key with the configured or a
+// remembered passphrase, or asks the user for it.
+func decryptKey(keyPath string, key []byte, opts Options) (ssh.Signer, error) {
+	passphrase := opts.Passphrase
+	if passphrase == "" {
+		if p, ok := passphrases.Load(keyPath); ok {
+			passphrase = p.(string)
+		}
+	}
+	if passphrase == "" {
+		if opts.Prompt == nil {
+			return nil, fmt.Errorf("key '%s' is encrypted and no key_passphrase is set", keyPath)
+		}
+		answer, ok := opts.Prompt(protocols.Prompt{
+			Title:   "Ключ SSH",
+			Message: fmt.Sprintf("Пароль ключа %s:", keyPath),
+		})
+		if !ok {
+			return nil, fmt.Errorf("passphrase for '%s' not entered", keyPath)
+		}
+		passphrase = answer
+	}
+
+	signer, err := ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+	if err != nil {
+		passphrases.Delete(keyPath)
+		return nil, err
+	}
+	if opts.Passphrase == "" {
+		passphrases.Store(keyPath, passphrase)
+	}
+	return signer, nil
+}
+
+// certificateSigner pairs an OpenSSH user certificate with its key.
+func certificateSigner(certPath string, signer ssh.Signer) (ssh.Signer, error) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate '%s': %w", certPath, err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate '%s': %w", certPath, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not an OpenSSH certificate", certPath)
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("certificate '%s' does not match the key: %w", certPath, err)
+	}
+	return certSigner, nil
+}
+
+// keyboardInteractive answers password-like questions with the configured
+// password once and asks the user about everything else, e.g. 2FA codes.
+func keyboardInteractive(opts Options) ssh.KeyboardInteractiveChallenge {
+	passwordUsed := false
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i, q := range questions {
+			if opts.Password != "" && !passwordUsed && !echos[i] && strings.Contains(strings.ToLower(q), "password") {
+				answers[i] = opts.Password
+				passwordUsed = true
+				continue
+			}
+			if opts.Prompt == nil {
+				return nil, fmt.Errorf("server asked %q and there is no way to answer", strings.TrimSpace(q))
+			}
+
+			title := name
+			if title == "" {
+				title = "SSH"
+			}
+			msg := strings.TrimSpace(q)
+			if instruction != "" {
+				msg = strings.TrimSpace(instruction) + "\n" + msg
+			}
+			answer, ok := opts.Prompt(protocols.Prompt{Title: title, Message: msg, Echo: echos[i]})
+			if !ok {
+				return nil, fmt.Errorf("keyboard-interactive authentication cancelled")
+			}
+			answers[i] = answer
+		}
+		return answers, nil
+	}
+}
+
+// errNoAgent means no ssh-agent is configured.
+var errNoAgent = errors.New("no ssh-agent")
@@ -0,0 +1,64 @@
+// Package sshhelper defines the handshake between the SSH tunnel and the
+// vpn-ssh-helper program it runs on the server.
+//
+// After setting up the TUN device the helper prints a single line
+//
+//	VPNHELPER <version> READY <tun> <client address>
+//
+// and then forwards packets framed with a big-endian uint16 length, the
+// same framing as the Python fallback script.
+package sshhelper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersion is bumped whenever the helper's arguments, handshake or
+// framing change. The client refuses helpers speaking another version.
+const ProtocolVersion = 2
+
+// magic starts every handshake line.
+const magic = "VPNHELPER"
+
+// ReadyLine returns the line the helper prints once tun is up with the
+// client address it allocated.
+func ReadyLine(tun, client string) string {
+	return fmt.Sprintf("%s %d READY %s %s\n", magic, ProtocolVersion, tun, client)
+}
+
+// ParseReady checks a handshake line and returns the remote TUN device
+// name and the client address.
+func ParseReady(line string) (tun, client string, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != magic {
+		return "", "", fmt.Errorf("unexpected handshake from helper: %q", line)
+	}
+	v, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", "", fmt.Errorf("unexpected handshake from helper: %q", line)
+	}
+	if v != ProtocolVersion {
+		return "", "", fmt.Errorf("helper speaks protocol %d, want %d", v, ProtocolVersion)
+	}
+	if len(fields) != 5 || fields[2] != "READY" {
+		return "", "", fmt.Errorf("unexpected handshake from helper: %q", line)
+	}
+	return fields[3], fields[4], nil
+}
+
+// Arch maps the output of `uname -m` to a GOARCH the helper is built for.
+func Arch(machine string) (string, bool) {
+	switch strings.TrimSpace(machine) {
+	case "x86_64", "amd64":
+		return "amd64", true
+	case "aarch64", "arm64", "armv8l":
+		return "arm64", true
+	case "armv6l", "armv7l":
+		return "arm", true
+	case "i386", "i686":
+		return "386", true
+	}
+	return "", false
+}
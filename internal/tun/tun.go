@@ -9,6 +9,11 @@ import (
 	"golang.zx2c4.com/wireguard/tun"
 )
 
+// Offset is the headroom to leave in front of each packet passed to
+// ReadBatch and WriteBatch, room for the packet information header of
+// utun on macOS and the virtio header of offloading devices on Linux.
+const Offset = 16
+
 // Adapter represents a TUN adapter.
 type Adapter struct {
 	mu      sync.Mutex
@@ -138,6 +143,8 @@ func (a *Adapter) InterfaceIndex() uint32 {
 
 // MTU returns the MTU.
 func (a *Adapter) MTU() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	return a.mtu
 }
 
@@ -148,26 +155,31 @@ func (a *Adapter) IsUp() bool {
 	return a.isUp
 }
 
-// Read reads a packet from the TUN device.
-func (a *Adapter) Read(buf []byte, offset int) (int, error) {
+// BatchSize returns how many packets one ReadBatch may return.
+func (a *Adapter) BatchSize() int {
 	if a.device == nil {
-		return 0, fmt.Errorf("adapter not created")
-	}
-	sizes := make([]int, 1)
-	n, err := a.device.Read([][]byte{buf[offset:]}, sizes, offset)
-	if err != nil {
-		return 0, err
+		return 1
 	}
-	if n == 0 {
-		return 0, nil
+	return a.device.BatchSize()
+}
+
+// ReadBatch reads packets from the TUN device into bufs[i][offset:],
+// storing their lengths in sizes, and returns the number of packets.
+// bufs and sizes should hold BatchSize entries and offset be at least
+// Offset.
+func (a *Adapter) ReadBatch(bufs [][]byte, sizes []int, offset int) (int, error) {
+	if a.device == nil {
+		return 0, fmt.Errorf("adapter not created")
 	}
-	return sizes[0], nil
+	return a.device.Read(bufs, sizes, offset)
 }
 
-// Write writes a packet to the TUN device.
-func (a *Adapter) Write(buf []byte, offset int) (int, error) {
+// WriteBatch writes the packets bufs[i][offset:] to the TUN device.
+// offset must be at least Offset; the headroom and spare capacity of the
+// buffers may be overwritten.
+func (a *Adapter) WriteBatch(bufs [][]byte, offset int) (int, error) {
 	if a.device == nil {
 		return 0, fmt.Errorf("adapter not created")
 	}
-	return a.device.Write([][]byte{buf[offset:]}, offset)
+	return a.device.Write(bufs, offset)
 }
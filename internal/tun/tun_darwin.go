@@ -7,6 +7,7 @@ import (
 	"net/netip"
 	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -104,3 +105,21 @@ func prefixToMask(bits int) string {
 	}
 	return strings.Join(parts, ".")
 }
+
+// SetMTU changes the MTU of the running adapter (macOS).
+func (a *Adapter) SetMTU(mtu int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.device == nil {
+		return fmt.Errorf("adapter not created")
+	}
+
+	cmd := exec.Command("ifconfig", a.name, "mtu", strconv.Itoa(mtu))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set MTU: %w: %s", err, string(out))
+	}
+
+	a.mtu = mtu
+	return nil
+}
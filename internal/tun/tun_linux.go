@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/netip"
 	"os/exec"
+	"strconv"
 )
 
 // normalizeInterfaceName returns the name as-is on Linux (no restrictions).
@@ -63,3 +64,21 @@ func (a *Adapter) Down() error {
 	a.isUp = false
 	return nil
 }
+
+// SetMTU changes the MTU of the running adapter (Linux).
+func (a *Adapter) SetMTU(mtu int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.device == nil {
+		return fmt.Errorf("adapter not created")
+	}
+
+	cmd := exec.Command("ip", "link", "set", "dev", a.name, "mtu", strconv.Itoa(mtu))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set MTU: %w: %s", err, string(out))
+	}
+
+	a.mtu = mtu
+	return nil
+}
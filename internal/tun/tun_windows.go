@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/netip"
 	"os/exec"
+	"strconv"
 
 	"github.com/user/vpn-client/internal/procutil"
 )
@@ -81,3 +82,24 @@ func (a *Adapter) Down() error {
 	a.isUp = false
 	return nil
 }
+
+// SetMTU changes the MTU of the running adapter (Windows).
+func (a *Adapter) SetMTU(mtu int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.device == nil {
+		return fmt.Errorf("adapter not created")
+	}
+
+	for _, family := range []string{"ipv4", "ipv6"} {
+		cmd := procutil.HideWindow(exec.Command("netsh", "interface", family, "set", "subinterface",
+			a.name, "mtu="+strconv.Itoa(mtu), "store=active"))
+		if out, err := cmd.CombinedOutput(); err != nil && family == "ipv4" {
+			return fmt.Errorf("failed to set MTU: %w: %s", err, string(out))
+		}
+	}
+
+	a.mtu = mtu
+	return nil
+}
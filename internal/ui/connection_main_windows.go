@@ -25,6 +25,7 @@ var (
 	cwLblSent     *walk.Label
 	cwLblReceived *walk.Label
 	cwLblLocalIP  *walk.Label
+	cwLblMTU      *walk.Label
 	cwLblStatus   *walk.Label
 	cwDotImage    *walk.ImageView
 	cwBtnConnect  *walk.PushButton
@@ -117,6 +118,11 @@ func ShowConnectionWindow() {
 						Label{AssignTo: &cwLblLocalIP, Text: "—"},
 						HSpacer{},
 					}},
+					Composite{Layout: HBox{MarginsZero: true, Spacing: 8}, Children: []Widget{
+						Label{Text: "MTU:", Font: Font{Bold: true}, MinSize: Size{Width: 120}, MaxSize: Size{Width: 120}},
+						Label{AssignTo: &cwLblMTU, Text: "—"},
+						HSpacer{},
+					}},
 				},
 			},
 
@@ -129,7 +135,7 @@ func ShowConnectionWindow() {
 					Label{Text: "Протокол:", MinSize: Size{Width: 80}},
 					ComboBox{
 						AssignTo: &cwProtocolCB,
-						Model:    []string{"wireguard", "openvpn", "ssh"},
+						Model:    protocolNames,
 					},
 				},
 			},
@@ -179,7 +185,7 @@ func ShowConnectionWindow() {
 	connWindowMu.Unlock()
 
 	// Set protocol from config
-	for i, p := range []string{"wireguard", "openvpn", "ssh"} {
+	for i, p := range protocolNames {
 		if p == appCfg.Protocol {
 			cwProtocolCB.SetCurrentIndex(i)
 			break
@@ -46,8 +46,11 @@ func cwRefreshUI(status *core.StatusPayload) {
 		cwLblServer.SetText(status.ServerAddress)
 		cwLblProtocol.SetText(strings.ToUpper(status.Protocol))
 		cwLblLocalIP.SetText(status.LocalIP)
-		cwLblSent.SetText(formatBytes(status.BytesSent))
-		cwLblReceived.SetText(formatBytes(status.BytesReceived))
+		if status.MTU > 0 {
+			cwLblMTU.SetText(fmt.Sprintf("%d", status.MTU))
+		}
+		cwLblSent.SetText(formatTransfer(status.BytesSent, status.RateSent))
+		cwLblReceived.SetText(formatTransfer(status.BytesReceived, status.RateReceived))
 		if !status.ConnectedAt.IsZero() {
 			cwLblUptime.SetText(formatDuration(time.Since(status.ConnectedAt)))
 		}
@@ -95,6 +98,7 @@ func cwResetLabels() {
 	cwLblSent.SetText("0 B")
 	cwLblReceived.SetText("0 B")
 	cwLblLocalIP.SetText("—")
+	cwLblMTU.SetText("—")
 }
 
 func formatBytes(b uint64) string {
@@ -115,6 +119,11 @@ func formatBytes(b uint64) string {
 	}
 }
 
+// formatTransfer shows a byte total with its current rate.
+func formatTransfer(total, rate uint64) string {
+	return fmt.Sprintf("%s (%s/с)", formatBytes(total), formatBytes(rate))
+}
+
 func formatDuration(d time.Duration) string {
 	h := int(d.Hours())
 	m := int(d.Minutes()) % 60
@@ -0,0 +1,32 @@
+//go:build darwin
+
+package ui
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/user/vpn-client/internal/protocols"
+)
+
+// showPrompt asks the user for input in an AppleScript dialog, e.g. for an OTP.
+func showPrompt(p protocols.Prompt) (string, bool) {
+	script := fmt.Sprintf(`text returned of (display dialog %s with title %s default answer ""`,
+		appleScriptString(p.Message), appleScriptString(p.Title))
+	if !p.Echo {
+		script += " with hidden answer"
+	}
+	script += ")"
+
+	// Exits non-zero when the user cancels
+	out, err := exec.Command("osascript", "-e", script).Output()
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(string(out), "\n"), true
+}
+
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
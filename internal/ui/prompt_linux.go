@@ -0,0 +1,71 @@
+//go:build linux
+
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+)
+
+// showPrompt asks the user for input with zenity or kdialog, falling back to
+// the controlling terminal when no dialog tool is installed.
+func showPrompt(p protocols.Prompt) (string, bool) {
+	var cmd *exec.Cmd
+	if path, err := exec.LookPath("zenity"); err == nil {
+		args := []string{"--entry", "--title", p.Title, "--text", p.Message}
+		if !p.Echo {
+			args = append(args, "--hide-text")
+		}
+		cmd = exec.Command(path, args...)
+	} else if path, err := exec.LookPath("kdialog"); err == nil {
+		mode := "--inputbox"
+		if !p.Echo {
+			mode = "--password"
+		}
+		cmd = exec.Command(path, "--title", p.Title, mode, p.Message)
+	}
+
+	if cmd != nil {
+		// Both exit non-zero when the user cancels
+		out, err := cmd.Output()
+		if err != nil {
+			return "", false
+		}
+		return strings.TrimSuffix(string(out), "\n"), true
+	}
+
+	answer, err := promptTerminal(p)
+	if err != nil {
+		logger.Warning("Cannot ask for input: %v", err)
+		return "", false
+	}
+	return answer, true
+}
+
+// promptTerminal reads the answer from the controlling terminal.
+func promptTerminal(p protocols.Prompt) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", errors.New("no dialog tool (zenity, kdialog) and no terminal")
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(tty, "%s: %s ", p.Title, p.Message)
+
+	if !p.Echo {
+		b, err := term.ReadPassword(int(tty.Fd()))
+		fmt.Fprintln(tty)
+		return string(b), err
+	}
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
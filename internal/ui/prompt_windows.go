@@ -0,0 +1,61 @@
+//go:build windows
+
+package ui
+
+import (
+	"runtime"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+
+	"github.com/user/vpn-client/internal/logger"
+	"github.com/user/vpn-client/internal/protocols"
+)
+
+// showPrompt asks the user for input in a modal dialog, e.g. for an OTP.
+func showPrompt(p protocols.Prompt) (string, bool) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var dlg *walk.Dialog
+	var edit *walk.LineEdit
+	var okPB, cancelPB *walk.PushButton
+	var answer string
+
+	res, err := Dialog{
+		AssignTo:      &dlg,
+		Title:         p.Title,
+		DefaultButton: &okPB,
+		CancelButton:  &cancelPB,
+		MinSize:       Size{Width: 360, Height: 140},
+		Layout:        VBox{Margins: Margins{Left: 12, Top: 12, Right: 12, Bottom: 12}, Spacing: 8},
+		Children: []Widget{
+			Label{Text: p.Message},
+			LineEdit{AssignTo: &edit, PasswordMode: !p.Echo},
+			Composite{
+				Layout: HBox{MarginsZero: true},
+				Children: []Widget{
+					HSpacer{},
+					PushButton{
+						AssignTo: &okPB,
+						Text:     "OK",
+						OnClicked: func() {
+							answer = edit.Text()
+							dlg.Accept()
+						},
+					},
+					PushButton{
+						AssignTo:  &cancelPB,
+						Text:      "Отмена",
+						OnClicked: func() { dlg.Cancel() },
+					},
+				},
+			},
+		},
+	}.Run(nil)
+	if err != nil {
+		logger.Error("Failed to show prompt: %v", err)
+		return "", false
+	}
+	return answer, res == walk.DlgCmdOK
+}
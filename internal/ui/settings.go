@@ -10,7 +10,12 @@ import (
 	"github.com/user/vpn-client/internal/logger"
 )
 
+// protocolNames lists the protocols selectable in the UI.
+var protocolNames = []string{"wireguard", "wireguard-userspace", "openvpn", "ssh", "ssh-proxy"}
+
 // AppConfig represents the application configuration for the settings UI.
+// It covers only the fields the UI edits; saveAppConfig merges it into the
+// existing file so that all other settings are preserved.
 type AppConfig struct {
 	Version   int    `yaml:"version"`
 	Protocol  string `yaml:"protocol"`
@@ -19,6 +24,7 @@ type AppConfig struct {
 	WireGuard struct {
 		PrivateKey string `yaml:"private_key"`
 		Address    string `yaml:"address"`
+		DNS        string `yaml:"dns"`
 		Peer       struct {
 			PublicKey           string `yaml:"public_key"`
 			Endpoint            string `yaml:"endpoint"`
@@ -36,9 +42,9 @@ type AppConfig struct {
 		Host          string `yaml:"host"`
 		Port          int    `yaml:"port"`
 		User          string `yaml:"user"`
-		KeyPath       string `yaml:"key_path,omitempty"`
-		RemoteTunAddr string `yaml:"remote_tun_addr,omitempty"`
-		LocalTunAddr  string `yaml:"local_tun_addr,omitempty"`
+		KeyPath       string `yaml:"key_path"`
+		RemoteTunAddr string `yaml:"remote_tun_addr"`
+		LocalTunAddr  string `yaml:"local_tun_addr"`
 	} `yaml:"ssh"`
 
 	Routing struct {
@@ -55,15 +61,21 @@ type AppConfig struct {
 	} `yaml:"dns"`
 
 	Interface struct {
-		Name   string `yaml:"name"`
-		MTU    int    `yaml:"mtu"`
-		Metric int    `yaml:"metric"`
+		Name     string        `yaml:"name"`
+		MTU      vpnconfig.MTU `yaml:"mtu"`
+		Metric   int           `yaml:"metric"`
+		ClampMSS bool          `yaml:"clamp_mss"`
 	} `yaml:"interface"`
 
 	Killswitch struct {
 		Enabled  bool `yaml:"enabled"`
 		AllowLAN bool `yaml:"allow_lan"`
 	} `yaml:"killswitch"`
+
+	Proxy struct {
+		SOCKSListen string `yaml:"socks_listen"`
+		HTTPListen  string `yaml:"http_listen"`
+	} `yaml:"proxy"`
 }
 
 func loadAppConfig() *AppConfig {
@@ -100,9 +112,33 @@ func saveAppConfig(cfg *AppConfig) error {
 		return err
 	}
 
+	// Overlay the UI fields on the current file instead of replacing it.
+	if existing, err := os.ReadFile(configPath); err == nil {
+		var base, edited map[string]interface{}
+		if yaml.Unmarshal(existing, &base) == nil && yaml.Unmarshal(data, &edited) == nil && base != nil {
+			mergeYAML(base, edited)
+			if merged, err := yaml.Marshal(base); err == nil {
+				data = merged
+			}
+		}
+	}
+
 	return os.WriteFile(configPath, data, 0644)
 }
 
+// mergeYAML copies src into dst, recursing into nested sections.
+func mergeYAML(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if sv, ok := v.(map[string]interface{}); ok {
+			if dv, ok := dst[k].(map[string]interface{}); ok {
+				mergeYAML(dv, sv)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
 func clearLogFile() {
 	logger.ClearLogs()
 }
@@ -8,6 +8,8 @@ import (
 
 	"github.com/lxn/walk"
 	. "github.com/lxn/walk/declarative"
+
+	vpnconfig "github.com/user/vpn-client/internal/config"
 )
 
 // ShowSettingsWindow displays the settings window (Windows — lxn/walk GUI).
@@ -36,7 +38,7 @@ func ShowSettingsWindow() {
 	var dnsServers, dnsDomains *walk.LineEdit
 	var splitDNSCheck *walk.CheckBox
 	var ifaceName, ifaceMTU, ifaceMetric *walk.LineEdit
-	var killswitchCheck, allowLANCheck *walk.CheckBox
+	var killswitchCheck, allowLANCheck, clampMSSCheck *walk.CheckBox
 
 	// Logs widget
 	var logsTextEdit *walk.TextEdit
@@ -47,9 +49,9 @@ func ShowSettingsWindow() {
 	// Function to show/hide protocol settings
 	updateProtocolVisibility := func() {
 		protocol := protocolCombo.Text()
-		wgContainer.SetVisible(protocol == "wireguard")
+		wgContainer.SetVisible(protocol == "wireguard" || protocol == "wireguard-userspace")
 		ovpnContainer.SetVisible(protocol == "openvpn")
-		sshContainer.SetVisible(protocol == "ssh")
+		sshContainer.SetVisible(protocol == "ssh" || protocol == "ssh-proxy")
 	}
 
 	MainWindow{
@@ -72,7 +74,7 @@ func ShowSettingsWindow() {
 									Label{Text: "Протокол:"},
 									ComboBox{
 										AssignTo: &protocolCombo,
-										Model:    []string{"wireguard", "openvpn", "ssh"},
+										Model:    protocolNames,
 										OnCurrentIndexChanged: func() {
 											updateProtocolVisibility()
 										},
@@ -175,10 +177,12 @@ func ShowSettingsWindow() {
 						Children: []Widget{
 							Label{Text: "Имя интерфейса:"},
 							LineEdit{AssignTo: &ifaceName},
-							Label{Text: "MTU:"},
+							Label{Text: "MTU (число или auto):"},
 							LineEdit{AssignTo: &ifaceMTU},
 							Label{Text: "Метрика маршрута:"},
 							LineEdit{AssignTo: &ifaceMetric},
+							Label{Text: ""},
+							CheckBox{AssignTo: &clampMSSCheck, Text: "Ограничивать TCP MSS (clamp-mss-to-pmtu)"},
 							HSpacer{},
 							HSpacer{},
 							Label{Text: "Kill Switch:"},
@@ -292,7 +296,7 @@ func ShowSettingsWindow() {
 							}
 
 							config.Interface.Name = ifaceName.Text()
-							if m, err := strconv.Atoi(strings.TrimSpace(ifaceMTU.Text())); err == nil {
+							if m, err := vpnconfig.ParseMTU(ifaceMTU.Text()); err == nil {
 								config.Interface.MTU = m
 							} else {
 								config.Interface.MTU = 0
@@ -302,6 +306,7 @@ func ShowSettingsWindow() {
 							} else {
 								config.Interface.Metric = 0
 							}
+							config.Interface.ClampMSS = clampMSSCheck.Checked()
 
 							// Kill Switch
 							config.Killswitch.Enabled = killswitchCheck.Checked()
@@ -324,7 +329,7 @@ func ShowSettingsWindow() {
 	}.Create()
 
 	// Populate fields
-	for i, p := range []string{"wireguard", "openvpn", "ssh"} {
+	for i, p := range protocolNames {
 		if p == config.Protocol {
 			protocolCombo.SetCurrentIndex(i)
 			break
@@ -353,8 +358,9 @@ func ShowSettingsWindow() {
 	dnsDomains.SetText(strings.Join(config.DNS.Domains, ", "))
 
 	ifaceName.SetText(config.Interface.Name)
-	ifaceMTU.SetText(strconv.Itoa(config.Interface.MTU))
+	ifaceMTU.SetText(config.Interface.MTU.String())
 	ifaceMetric.SetText(strconv.Itoa(config.Interface.Metric))
+	clampMSSCheck.SetChecked(config.Interface.ClampMSS)
 
 	killswitchCheck.SetChecked(config.Killswitch.Enabled)
 	allowLANCheck.SetChecked(config.Killswitch.AllowLAN)
@@ -47,6 +47,9 @@ func Run() {
 		updateUI(status)
 	})
 
+	// Ask for OTPs and challenge responses with a dialog
+	service.SetPromptHandler(showPrompt)
+
 	// Start the service (handles auto-connect if configured)
 	if err := service.Start(); err != nil {
 		log.Fatalf("Failed to start VPN service: %v", err)
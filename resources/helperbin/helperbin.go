@@ -0,0 +1,21 @@
+// Package helperbin embeds the vpn-ssh-helper binaries the SSH tunnel
+// uploads to Linux servers. They are built into bin/ by
+// `make build-ssh-helper`; without them the tunnel uses its Python script.
+package helperbin
+
+import (
+	"embed"
+)
+
+//go:embed bin
+var binaries embed.FS
+
+// Binary returns the helper built for the given Linux GOARCH, or nil if
+// this build does not include it.
+func Binary(goarch string) []byte {
+	data, err := binaries.ReadFile("bin/vpn-ssh-helper-linux-" + goarch)
+	if err != nil {
+		return nil
+	}
+	return data
+}